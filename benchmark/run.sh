@@ -0,0 +1,21 @@
+#!/bin/sh
+# Runs every benchmark with the glox binaries given as arguments, best of 3 runs each.
+#
+#   benchmark/run.sh /tmp/glox-old /tmp/glox-new
+set -e
+dir=$(dirname "$0")
+
+for bench in "$dir"/*.lox; do
+	for glox in "$@"; do
+		best=
+		for run in 1 2 3; do
+			start=$(date +%s%N)
+			"$glox" "$bench" > /dev/null
+			elapsed=$(( ($(date +%s%N) - start) / 1000000 ))
+			if [ -z "$best" ] || [ "$elapsed" -lt "$best" ]; then
+				best=$elapsed
+			fi
+		done
+		printf '%-24s %-20s %6d ms\n' "$(basename "$bench")" "$(basename "$glox")" "$best"
+	done
+done
@@ -0,0 +1,13 @@
+package lox
+
+import "github.com/blackLearning/glox/lox/internal/core"
+
+// Capabilities group the natives reaching out of the program, scripts can only call the ones Options.Allow lists.
+// net has no natives of its own yet, hosts define theirs with Runtime.SetNative.
+const (
+	CapabilityIO      = core.CapabilityIO
+	CapabilityOS      = core.CapabilityOS
+	CapabilityTime    = core.CapabilityTime
+	CapabilityNet     = core.CapabilityNet
+	CapabilityProcess = core.CapabilityProcess
+)
@@ -0,0 +1,13 @@
+// Package lox compiles and runs lox programs, on a tree-walking interpreter or on a bytecode VM.
+//
+// A Runtime is what a go host embeds the language with:
+//
+//	runtime, err := lox.NewRuntime(lox.Options{})
+//	runtime.SetGlobal("limit", 10)
+//	value, err := runtime.Eval(`fun double(n) { return n * 2; } double(limit);`)
+//	result, err := runtime.Call("double", 21)
+//
+// Values go to lox through ToLox and come back through ToGo,
+// which document how each kind of value is converted.
+// Programs that fail to compile, and errors nothing caught while they ran, are returned as *Error.
+package lox
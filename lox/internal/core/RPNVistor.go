@@ -1,4 +1,4 @@
-package main
+package core
 
 import (
 	"fmt"
@@ -58,6 +58,21 @@ func (v RPNVisitor) visitThisExpr(expr ThisExpr) interface{} {
 func (v RPNVisitor) visitSuperExpr(expr SuperExpr) interface{} {
 	return "1 1 +"
 }
+func (v RPNVisitor) visitInterpolationExpr(expr InterpolationExpr) interface{} {
+	return "1 1 +"
+}
+func (v RPNVisitor) visitListExpr(expr ListExpr) interface{} {
+	return "1 1 +"
+}
+func (v RPNVisitor) visitMapExpr(expr MapExpr) interface{} {
+	return "1 1 +"
+}
+func (v RPNVisitor) visitIndexExpr(expr IndexExpr) interface{} {
+	return "1 1 +"
+}
+func (v RPNVisitor) visitIndexSetExpr(expr IndexSetExpr) interface{} {
+	return "1 1 +"
+}
 
 // func init() {
 // 	expression := BinaryExpr{
@@ -0,0 +1,297 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// AstPrinter prints the AST back as lox, with every operation parenthesized,
+// so what the parser and the optimizer made of a program can be checked
+type AstPrinter struct {
+	out io.Writer
+	// depth is how many blocks deep the statement being printed is
+	depth int
+}
+
+func (v AstPrinter) print(expr Expr, out io.Writer) {
+	str, _ := expr.accept(v).(string)
+	out.Write([]byte(str))
+}
+
+// printStmts prints a program one statement a line, indented by blocks
+func (v *AstPrinter) printStmts(stmts []Stmt) {
+	for _, stmt := range stmts {
+		stmt.accept(v)
+	}
+}
+
+func (v *AstPrinter) line(format string, args ...interface{}) {
+	fmt.Fprint(v.out, strings.Repeat("  ", v.depth))
+	fmt.Fprintf(v.out, format+"\n", args...)
+}
+
+// body prints the statement run by header, blocks open on the line of the header
+func (v *AstPrinter) body(header string, stmt Stmt) {
+	if block, ok := stmt.(BlockStmt); ok {
+		v.line("%s {", header)
+		v.depth++
+		v.printStmts(block.statements)
+		v.depth--
+		v.line("}")
+		return
+	}
+	v.line("%s", header)
+	v.depth++
+	stmt.accept(v)
+	v.depth--
+}
+
+func (v AstPrinter) expr(expr Expr) string {
+	return expr.accept(v).(string)
+}
+
+func (v AstPrinter) exprs(exprs []Expr) string {
+	strs := make([]string, len(exprs))
+	for i, expr := range exprs {
+		strs[i] = v.expr(expr)
+	}
+	return strings.Join(strs, ", ")
+}
+
+func paramList(tokens []Token) string {
+	names := make([]string, len(tokens))
+	for i, token := range tokens {
+		names[i] = token.literal
+	}
+	return strings.Join(names, ", ")
+}
+
+func labelPrefix(token Token) string {
+	if token.literal == "" {
+		return ""
+	}
+	return token.literal + ": "
+}
+
+/* statements */
+
+func (v *AstPrinter) visitExpressionStmt(stmt ExpressionStmt) {
+	v.line("%s;", v.expr(stmt.expression))
+}
+
+func (v *AstPrinter) visitBlockStmt(stmt BlockStmt) {
+	v.line("{")
+	v.depth++
+	v.printStmts(stmt.statements)
+	v.depth--
+	v.line("}")
+}
+
+func (v *AstPrinter) visitVarStmt(stmt VarStmt) {
+	if stmt.init == nil {
+		v.line("var %s;", stmt.name.literal)
+		return
+	}
+	v.line("var %s = %s;", stmt.name.literal, v.expr(stmt.init))
+}
+
+func (v *AstPrinter) visitClassStmt(stmt ClassStmt) {
+	header := "class " + stmt.name.literal
+	if stmt.super != nil {
+		header += " < " + stmt.super.name.literal
+	}
+	v.line("%s {", header)
+	v.depth++
+	for _, method := range stmt.staticMethods {
+		v.body(fmt.Sprintf("static %s(%s)", method.name.literal, paramList(method.params)), method.body)
+	}
+	for _, method := range stmt.methods {
+		v.body(fmt.Sprintf("%s(%s)", method.name.literal, paramList(method.params)), method.body)
+	}
+	v.depth--
+	v.line("}")
+}
+
+func (v *AstPrinter) visitReturnStmt(stmt ReturnStmt) {
+	if stmt.value == nil {
+		v.line("return;")
+		return
+	}
+	v.line("return %s;", v.expr(stmt.value))
+}
+
+func (v *AstPrinter) visitFunStmt(stmt FunStmt) {
+	v.body(fmt.Sprintf("fun %s(%s)", stmt.name.literal, paramList(stmt.params)), stmt.body)
+}
+
+func (v *AstPrinter) visitIfStmt(stmt IfStmt) {
+	v.body(fmt.Sprintf("if (%s)", v.expr(stmt.condition)), stmt.consequent)
+	if stmt.alternate != nil {
+		v.body("else", stmt.alternate)
+	}
+}
+
+func (v *AstPrinter) visitWhileStmt(stmt WhileStmt) {
+	if stmt.increment != nil {
+		v.body(fmt.Sprintf("%sfor (; %s; %s)", labelPrefix(stmt.label), v.expr(stmt.condition), v.expr(stmt.increment)), stmt.body)
+		return
+	}
+	v.body(fmt.Sprintf("%swhile (%s)", labelPrefix(stmt.label), v.expr(stmt.condition)), stmt.body)
+}
+
+func (v *AstPrinter) visitForInStmt(stmt ForInStmt) {
+	v.body(fmt.Sprintf("%sfor (var %s in %s)", labelPrefix(stmt.label), stmt.name.literal, v.expr(stmt.iterable)), stmt.body)
+}
+
+func (v *AstPrinter) visitThrowStmt(stmt ThrowStmt) {
+	v.line("throw %s;", v.expr(stmt.value))
+}
+
+func (v *AstPrinter) visitTryStmt(stmt TryStmt) {
+	v.body("try", stmt.body)
+	if stmt.catchBody != nil {
+		v.body("catch ("+stmt.name.literal+")", *stmt.catchBody)
+	}
+	if stmt.finallyBody != nil {
+		v.body("finally", *stmt.finallyBody)
+	}
+}
+
+func (v *AstPrinter) visitImportStmt(stmt ImportStmt) {
+	v.line("import %s as %s;", stmt.path.literal, stmt.name.literal)
+}
+
+func (v *AstPrinter) visitExportStmt(stmt ExportStmt) {
+	fmt.Fprint(v.out, strings.Repeat("  ", v.depth)+"export ")
+	// the declaration goes on the same line as export
+	depth := v.depth
+	v.depth = 0
+	stmt.declaration.accept(v)
+	v.depth = depth
+}
+
+func (v *AstPrinter) visitBreakStmt(stmt BreakStmt) {
+	if stmt.label.literal != "" {
+		v.line("break %s;", stmt.label.literal)
+		return
+	}
+	v.line("break;")
+}
+
+func (v *AstPrinter) visitContinueStmt(stmt ContinueStmt) {
+	if stmt.label.literal != "" {
+		v.line("continue %s;", stmt.label.literal)
+		return
+	}
+	v.line("continue;")
+}
+
+/* expressions */
+
+func (v AstPrinter) visitBinaryExpr(expr BinaryExpr) interface{} {
+	return "(" + v.expr(expr.left) + " " + expr.operator.literal + " " + v.expr(expr.right) + ")"
+}
+
+func (v AstPrinter) visitGroupingExpr(expr GroupingExpr) interface{} {
+	return "(" + v.expr(expr.expression) + ")"
+}
+
+func (v AstPrinter) visitLiteralExpr(expr LiteralExpr) interface{} {
+	return constantString(expr.value)
+}
+
+func (v AstPrinter) visitUnaryExpr(expr UnaryExpr) interface{} {
+	return expr.operator.literal + v.expr(expr.right)
+}
+
+func (v AstPrinter) visitConditionExpr(expr ConditionExpr) interface{} {
+	return "(" + v.expr(expr.test) + " ? " + v.expr(expr.consequent) + " : " + v.expr(expr.alternate) + ")"
+}
+
+func (v AstPrinter) visitSequenceExpr(expr SequenceExpr) interface{} {
+	return v.exprs(expr.exprs)
+}
+
+func (v AstPrinter) visitInterpolationExpr(expr InterpolationExpr) interface{} {
+	var b strings.Builder
+	b.WriteString("\"")
+	for _, part := range expr.parts {
+		if literal, ok := part.(LiteralExpr); ok {
+			b.WriteString(fmt.Sprint(literal.value))
+		} else {
+			b.WriteString("${")
+			b.WriteString(v.expr(part))
+			b.WriteString("}")
+		}
+	}
+	b.WriteString("\"")
+	return b.String()
+}
+
+func (v AstPrinter) visitListExpr(expr ListExpr) interface{} {
+	return "[" + v.exprs(expr.elements) + "]"
+}
+
+func (v AstPrinter) visitMapExpr(expr MapExpr) interface{} {
+	var b strings.Builder
+	b.WriteString("{")
+	for index, key := range expr.keys {
+		b.WriteString(v.expr(key))
+		b.WriteString(": ")
+		b.WriteString(v.expr(expr.values[index]))
+		if index < len(expr.keys)-1 {
+			b.WriteString(", ")
+		}
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func (v AstPrinter) visitIndexExpr(expr IndexExpr) interface{} {
+	return v.expr(expr.object) + "[" + v.expr(expr.index) + "]"
+}
+
+func (v AstPrinter) visitIndexSetExpr(expr IndexSetExpr) interface{} {
+	return v.expr(expr.object) + "[" + v.expr(expr.index) + "] = " + v.expr(expr.value)
+}
+
+func (v AstPrinter) visitAssignExpr(expr AssignExpr) interface{} {
+	return expr.left.literal + " = " + v.expr(expr.right)
+}
+
+func (v AstPrinter) visitIdentifierExpr(expr IdentifierExpr) interface{} {
+	return expr.name.literal
+}
+
+func (v AstPrinter) visitLogicalExpr(expr LogicalExpr) interface{} {
+	return "(" + v.expr(expr.left) + " " + expr.operator.literal + " " + v.expr(expr.right) + ")"
+}
+
+func (v AstPrinter) visitCallExpr(expr CallExpr) interface{} {
+	return v.expr(expr.callee) + "(" + v.exprs(expr.arguments) + ")"
+}
+
+func (v AstPrinter) visitFunExpr(expr FunExpr) interface{} {
+	// the body is printed as statements, one level deeper than the statement the function is in
+	var b strings.Builder
+	printer := &AstPrinter{&b, v.depth}
+	printer.body("fun ("+paramList(expr.params)+")", expr.body)
+	return strings.TrimSpace(b.String())
+}
+
+func (v AstPrinter) visitSetExpr(expr SetExpr) interface{} {
+	return v.expr(expr.object) + "." + expr.name.literal + " = " + v.expr(expr.value)
+}
+
+func (v AstPrinter) visitGetExpr(expr GetExpr) interface{} {
+	return v.expr(expr.object) + "." + expr.name.literal
+}
+
+func (v AstPrinter) visitThisExpr(expr ThisExpr) interface{} {
+	return "this"
+}
+
+func (v AstPrinter) visitSuperExpr(expr SuperExpr) interface{} {
+	return "super." + expr.method.literal
+}
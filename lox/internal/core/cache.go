@@ -0,0 +1,360 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+)
+
+// A .loxc file caches the bytecode of a lox file next to it:
+//
+//	"LOXC" u16 version, the sha256 of the source, the sha256 of the rest
+//	the names the module exports
+//	the function running its top level code, with the functions it makes as constants
+//
+// Numbers are uvarints, strings are a length then bytes.
+// Tokens are stored relative to their file, so the cache holds wherever the file is loaded.
+// A cache that doesn't match its checksum, or whose bytecode doesn't verify, is compiled again.
+const (
+	cacheMagic = "LOXC"
+	// cacheVersion is bumped whenever the bytecode or this format changes, older caches are compiled again
+	cacheVersion = 3
+)
+
+// tags of the values stored in constant tables and token lexemes
+const (
+	cacheNil byte = iota
+	cacheFalse
+	cacheTrue
+	cacheNumber
+	cacheString
+	cacheFunction
+)
+
+var errStaleCache = errors.New("stale bytecode cache")
+
+// cachePath is where the bytecode of the lox file at path is cached
+func cachePath(path string) string {
+	return strings.TrimSuffix(path, ".lox") + ".loxc"
+}
+
+// compileFile compiles the source of file name into bytecode, along with the names it exports.
+// Files are cached in their .loxc, which is used instead of scanning, parsing and resolving
+// for as long as the source hashes the same. Check l.hasError before using the result.
+func (l *Lox) compileFile(name string, src string) (*FunctionProto, map[string]bool) {
+	if name == replFilename {
+		stmts := l.compile(name, src)
+		if l.hasError {
+			return nil, nil
+		}
+		return l.compileBytecode(stmts), exportedNames(stmts)
+	}
+
+	hash := sha256.Sum256([]byte(src))
+	path := cachePath(name)
+	offset, line := l.end, l.lines[name]
+
+	if data, err := ioutil.ReadFile(path); err == nil {
+		if function, exports, err := decodeCache(data, hash, len(src), offset, line); err == nil {
+			l.hasError = false
+			l.hadRuntimeError = false
+			l.addSource(name, src)
+			return function, exports
+		}
+	}
+
+	stmts := l.compile(name, src)
+	if l.hasError {
+		return nil, nil
+	}
+	function := l.compileBytecode(stmts)
+	if l.hasError {
+		return nil, nil
+	}
+	exports := exportedNames(stmts)
+
+	// the cache only saves time, a directory we can't write to is no error
+	_ = ioutil.WriteFile(path, encodeCache(hash, function, exports, offset, line), 0644)
+	return function, exports
+}
+
+// cacheWriter encodes a cache, offset and line are where the tokens of the file start
+type cacheWriter struct {
+	buf    bytes.Buffer
+	offset int
+	line   int
+}
+
+func encodeCache(hash [sha256.Size]byte, function *FunctionProto, exports map[string]bool, offset int, line int) []byte {
+	w := &cacheWriter{offset: offset, line: line}
+	names := make([]string, 0, len(exports))
+	for name := range exports {
+		names = append(names, name)
+	}
+	// sorted, so the same source always caches to the same bytes
+	sort.Strings(names)
+	w.uint(len(names))
+	for _, name := range names {
+		w.string(name)
+	}
+	w.function(function)
+
+	payload := w.buf.Bytes()
+	checksum := sha256.Sum256(payload)
+	var data bytes.Buffer
+	data.WriteString(cacheMagic)
+	data.Write([]byte{cacheVersion >> 8, cacheVersion & 0xff})
+	data.Write(hash[:])
+	data.Write(checksum[:])
+	data.Write(payload)
+	return data.Bytes()
+}
+
+// uint writes n 7 bits at a time, low bits first, the high bit of a byte tells more follow
+func (w *cacheWriter) uint(n int) {
+	u := uint64(n)
+	for u >= 0x80 {
+		w.buf.WriteByte(byte(u) | 0x80)
+		u >>= 7
+	}
+	w.buf.WriteByte(byte(u))
+}
+
+func (w *cacheWriter) string(s string) {
+	w.uint(len(s))
+	w.buf.WriteString(s)
+}
+
+func (w *cacheWriter) value(value interface{}) {
+	switch value := value.(type) {
+	case nil:
+		w.buf.WriteByte(cacheNil)
+	case bool:
+		if value {
+			w.buf.WriteByte(cacheTrue)
+		} else {
+			w.buf.WriteByte(cacheFalse)
+		}
+	case float64:
+		w.buf.WriteByte(cacheNumber)
+		bits := math.Float64bits(value)
+		for shift := 56; shift >= 0; shift -= 8 {
+			w.buf.WriteByte(byte(bits >> uint(shift)))
+		}
+	case string:
+		w.buf.WriteByte(cacheString)
+		w.string(value)
+	case *FunctionProto:
+		w.buf.WriteByte(cacheFunction)
+		w.function(value)
+	default:
+		panic(fmt.Sprintf("cannot cache constant %T", value))
+	}
+}
+
+func (w *cacheWriter) function(function *FunctionProto) {
+	w.string(function.name)
+	w.string(function.className)
+	w.uint(function.arity)
+	w.uint(function.upvalueCount)
+	w.value(function.isInit)
+
+	chunk := function.chunk
+	w.uint(len(chunk.code))
+	w.buf.Write(chunk.code)
+	w.uint(len(chunk.constants))
+	for _, constant := range chunk.constants {
+		w.value(constant)
+	}
+
+	w.uint(len(chunk.tokens))
+	for _, token := range chunk.tokens {
+		w.token(token)
+	}
+	for _, ref := range chunk.refs {
+		w.uint(int(ref))
+	}
+}
+
+func (w *cacheWriter) token(token Token) {
+	w.uint(int(token.tokentype))
+	w.value(token.lexeme)
+	w.string(token.literal)
+	// tokens outside the file, like the zero token, have no place in it, they are written as offset 0
+	if token.offset < w.offset || token.line < w.line {
+		w.uint(0)
+		return
+	}
+	w.uint(token.offset - w.offset + 1)
+	w.uint(token.line - w.line)
+	w.uint(token.column)
+	w.uint(token.length)
+}
+
+// cacheReader decodes a cache, the first error it meets sticks and every read after it returns zeros
+type cacheReader struct {
+	data *bytes.Reader
+	// size is the length of the source, tokens must be inside it
+	size   int
+	offset int
+	line   int
+	err    error
+}
+
+// decodeCache reads a cache written for the source with hash and size bytes, placing its tokens at offset and line
+func decodeCache(data []byte, hash [sha256.Size]byte, size int, offset int, line int) (*FunctionProto, map[string]bool, error) {
+	header := len(cacheMagic) + 2 + 2*sha256.Size
+	if len(data) < header ||
+		string(data[:len(cacheMagic)]) != cacheMagic ||
+		int(data[4])<<8|int(data[5]) != cacheVersion ||
+		!bytes.Equal(data[6:6+sha256.Size], hash[:]) {
+		return nil, nil, errStaleCache
+	}
+	if checksum := sha256.Sum256(data[header:]); !bytes.Equal(data[6+sha256.Size:header], checksum[:]) {
+		return nil, nil, errStaleCache
+	}
+
+	r := &cacheReader{data: bytes.NewReader(data[header:]), size: size, offset: offset, line: line}
+	count := r.uint()
+	exports := make(map[string]bool, 0)
+	for i := 0; i < count && r.err == nil; i++ {
+		exports[r.string()] = true
+	}
+	function := r.function()
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	if r.data.Len() > 0 {
+		return nil, nil, errStaleCache
+	}
+	if err := verify(function); err != nil {
+		return nil, nil, err
+	}
+	return function, exports, nil
+}
+
+func (r *cacheReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *cacheReader) uint() int {
+	if r.err != nil {
+		return 0
+	}
+	var n uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := r.data.ReadByte()
+		if err != nil {
+			break
+		}
+		n |= uint64(b&0x7f) << shift
+		if b < 0x80 && n <= math.MaxInt32 {
+			return int(n)
+		}
+		if b < 0x80 {
+			break
+		}
+	}
+	r.fail(errStaleCache)
+	return 0
+}
+
+func (r *cacheReader) bytes(n int) []byte {
+	if r.err != nil || n > r.data.Len() {
+		r.fail(errStaleCache)
+		return nil
+	}
+	b := make([]byte, n)
+	r.data.Read(b)
+	return b
+}
+
+func (r *cacheReader) string() string {
+	return string(r.bytes(r.uint()))
+}
+
+func (r *cacheReader) value() interface{} {
+	tag := r.bytes(1)
+	if tag == nil {
+		return nil
+	}
+	switch tag[0] {
+	case cacheNil:
+		return nil
+	case cacheFalse:
+		return false
+	case cacheTrue:
+		return true
+	case cacheNumber:
+		var bits uint64
+		for _, b := range r.bytes(8) {
+			bits = bits<<8 | uint64(b)
+		}
+		return math.Float64frombits(bits)
+	case cacheString:
+		return r.string()
+	case cacheFunction:
+		return r.function()
+	}
+	r.fail(errStaleCache)
+	return nil
+}
+
+func (r *cacheReader) function() *FunctionProto {
+	function := &FunctionProto{}
+	function.name = r.string()
+	function.className = r.string()
+	function.arity = r.uint()
+	function.upvalueCount = r.uint()
+	function.isInit, _ = r.value().(bool)
+
+	chunk := &function.chunk
+	chunk.code = r.bytes(r.uint())
+	count := r.uint()
+	for i := 0; i < count && r.err == nil; i++ {
+		chunk.constants = append(chunk.constants, r.value())
+	}
+
+	count = r.uint()
+	for i := 0; i < count && r.err == nil; i++ {
+		chunk.tokens = append(chunk.tokens, r.token())
+	}
+	chunk.refs = make([]int32, len(chunk.code))
+	for i := range chunk.refs {
+		ref := r.uint()
+		if ref >= len(chunk.tokens) {
+			r.fail(errStaleCache)
+			return nil
+		}
+		chunk.refs[i] = int32(ref)
+	}
+	return function
+}
+
+func (r *cacheReader) token() Token {
+	token := Token{
+		tokentype: TokenType(r.uint()),
+		lexeme:    r.value(),
+		literal:   r.string(),
+		offset:    r.uint() - 1,
+	}
+	if token.offset < 0 {
+		return token
+	}
+	token.line = r.uint() + r.line
+	token.column = r.uint()
+	token.length = r.uint()
+	// diagnostics quote the source at the token
+	if token.offset+token.length > r.size {
+		r.fail(errStaleCache)
+	}
+	token.offset += r.offset
+	return token
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"crypto/sha256"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// cached compiles src as the second file of a Runtime, and returns its bytecode encoded as a cache,
+// along with what decodeCache needs to read it back
+func cached(t *testing.T, src string) (function *FunctionProto, data []byte, hash [sha256.Size]byte, offset int, line int) {
+	runtime, _ := NewRuntime(Options{})
+	l := runtime.lox
+	l.compile("first.lox", "var first = 1;\nprint(first);")
+	offset, line = l.end, l.lines["second.lox"]
+	stmts := l.compile("second.lox", src)
+	if l.hasError {
+		t.Fatalf("cannot compile %q: %v", src, l.takeError())
+	}
+	function = l.compileBytecode(stmts)
+	hash = sha256.Sum256([]byte(src))
+	return function, encodeCache(hash, function, exportedNames(stmts), offset, line), hash, offset, line
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	for name, src := range enginePrograms {
+		function, data, hash, offset, line := cached(t, src)
+		decoded, _, err := decodeCache(data, hash, len(src), offset, line)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded.chunk.code, function.chunk.code) {
+			t.Errorf("%s: the bytecode changed on the way through the cache", name)
+		}
+	}
+}
+
+func TestCacheRejectsCorruption(t *testing.T) {
+	src := enginePrograms["collections"]
+	_, data, hash, offset, line := cached(t, src)
+	header := len(cacheMagic) + 2 + 2*sha256.Size
+	random := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 500; i++ {
+		corrupted := append([]byte{}, data...)
+		at := header + random.Intn(len(data)-header)
+		corrupted[at] ^= byte(1 + random.Intn(255))
+		if _, _, err := decodeCache(corrupted, hash, len(src), offset, line); err == nil {
+			t.Fatalf("byte %d corrupted, but the cache still decodes", at)
+		}
+
+		// a payload corrupted with a matching checksum has to be caught by verify, without panicking
+		checksum := sha256.Sum256(corrupted[header:])
+		copy(corrupted[header-sha256.Size:], checksum[:])
+		decodeCache(corrupted, hash, len(src), offset, line)
+	}
+}
+
+func TestVerifyRejectsOutOfRangeOperands(t *testing.T) {
+	token := Token{}
+	tests := map[string][]byte{
+		"constant":      {byte(OP_CONSTANT), 0, 9, byte(OP_RETURN)},
+		"local":         {byte(OP_GET_LOCAL), 5, byte(OP_RETURN)},
+		"upvalue":       {byte(OP_GET_UPVALUE), 0, byte(OP_RETURN)},
+		"jump past end": {byte(OP_JUMP), 0, 40, byte(OP_NIL), byte(OP_RETURN)},
+		"loop":          {byte(OP_LOOP), 0, 9, byte(OP_NIL), byte(OP_RETURN)},
+		"mid operand":   {byte(OP_JUMP), 0, 1, byte(OP_CONSTANT), 0, 0, byte(OP_RETURN)},
+		"empty stack":   {byte(OP_POP), byte(OP_NIL), byte(OP_RETURN)},
+		"falls off":     {byte(OP_NIL)},
+		"unknown":       {0xff},
+	}
+	for name, code := range tests {
+		function := &FunctionProto{}
+		for _, b := range code {
+			function.chunk.write(b, token)
+		}
+		function.chunk.addConstant(1.0)
+		if verify(function) == nil {
+			t.Errorf("%s: bytecode verified", name)
+		}
+	}
+}
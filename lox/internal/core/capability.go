@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+)
+
+// capabilities natives are grouped by, see lox.CapabilityIO
+const (
+	CapabilityIO      = "io"
+	CapabilityOS      = "os"
+	CapabilityTime    = "time"
+	CapabilityNet     = "net"
+	CapabilityProcess = "process"
+)
+
+// capabilities holds the names Options.Allow and Runtime.SetNative accept
+var capabilities = map[string]bool{
+	CapabilityIO:      true,
+	CapabilityOS:      true,
+	CapabilityTime:    true,
+	CapabilityNet:     true,
+	CapabilityProcess: true,
+}
+
+// checkCapability makes sure capability is one glox knows, a typo would leave natives denied for no visible reason
+func checkCapability(capability string) error {
+	if !capabilities[capability] {
+		return fmt.Errorf("lox: unknown capability '%s', expected io, os, time, net or process", capability)
+	}
+	return nil
+}
+
+// defineNatives defines the natives of every capability, the ones of capabilities not allowed raise when called
+func (v Interpreter) defineNatives() {
+	v.defineNative(CapabilityTime, "clock", Clock{})
+
+	v.defineNative(CapabilityIO, "readFile", native("readFile", func(path string) (string, error) {
+		content, err := ioutil.ReadFile(path)
+		return string(content), err
+	}))
+	v.defineNative(CapabilityIO, "writeFile", native("writeFile", func(path string, content string) error {
+		return ioutil.WriteFile(path, []byte(content), 0644)
+	}))
+
+	v.defineNative(CapabilityOS, "getenv", native("getenv", func(name string) interface{} {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		return nil
+	}))
+
+	// exec runs a command and returns what it printed
+	v.defineNative(CapabilityProcess, "exec", native("exec", func(command string, args ...string) (string, error) {
+		output, err := exec.Command(command, args...).Output()
+		return string(output), err
+	}))
+}
+
+// defineNative defines a global native, which only calls through when capability is allowed
+func (v Interpreter) defineNative(capability string, name string, native Callable) {
+	if !v.lox.allowed[capability] {
+		native = deniedNative(capability, name)
+	}
+	v.global.define(name, native)
+}
+
+// deniedNative stands for a native of a capability that is not allowed, calling it raises a runtime error naming the capability
+func deniedNative(capability string, name string) *NativeFunction {
+	return &NativeFunction{name, -1, func(interpreter Interpreter, args []interface{}) interface{} {
+		panic(RuntimeError{
+			callSite(interpreter),
+			fmt.Sprintf("Calling %s needs the '%s' capability, which is not allowed", name, capability),
+		})
+	}}
+}
+
+// native makes a native of a go func the way ToLox does, for the natives glox comes with
+func native(name string, fn interface{}) *NativeFunction {
+	native, err := goFunction(name, reflect.ValueOf(fn))
+	if err != nil {
+		panic(err)
+	}
+	return native
+}
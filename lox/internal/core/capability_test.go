@@ -0,0 +1,25 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNativesCalledWithoutFrames(t *testing.T) {
+	runtime, _ := NewRuntime(Options{})
+	interpreter := runtime.lox.interpreter
+	raises := func(name string, native *NativeFunction, args ...interface{}) {
+		defer func() {
+			if err, ok := recover().(RuntimeError); !ok || err.token.offset >= 0 {
+				t.Errorf("%s raised %v", name, err)
+			}
+		}()
+		native.call(interpreter, args)
+	}
+	raises("a denied native", deniedNative(CapabilityIO, "readFile"))
+	raises("a go func failing", native("fail", func() error { return errors.New("boom") }))
+
+	if err, ok := ErrorConstructor.call(interpreter, []interface{}{"x"}).(*ErrorObject); !ok || err.message != "x" {
+		t.Errorf("Error(\"x\") made %v", err)
+	}
+}
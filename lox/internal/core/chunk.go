@@ -0,0 +1,231 @@
+package core
+
+import "math"
+
+// OpCode is a bytecode instruction, its operands follow it in the chunk
+type OpCode byte
+
+// Operands are one byte for slots, upvalues and argument counts,
+// two bytes (big endian) for constants, jumps and element counts.
+const (
+	// OP_CONSTANT pushes constants[u16]
+	OP_CONSTANT OpCode = iota
+	OP_NIL
+	OP_TRUE
+	OP_FALSE
+	OP_POP
+
+	// OP_GET_LOCAL pushes stack[base+u8], OP_SET_LOCAL stores the top there and leaves it
+	OP_GET_LOCAL
+	OP_SET_LOCAL
+	// globals are named by a string constant
+	OP_GET_GLOBAL
+	OP_DEFINE_GLOBAL
+	OP_SET_GLOBAL
+	OP_GET_UPVALUE
+	OP_SET_UPVALUE
+	// properties are named by a string constant
+	OP_GET_PROPERTY
+	OP_SET_PROPERTY
+	// OP_GET_SUPER pops this and the superclass, then pushes the method named by a constant, bound to this
+	OP_GET_SUPER
+	OP_GET_INDEX
+	OP_SET_INDEX
+
+	// binary operators pop two operands and push the result, see binary
+	OP_EQUAL
+	OP_NOT_EQUAL
+	OP_GREATER
+	OP_GREATER_EQUAL
+	OP_LESS
+	OP_LESS_EQUAL
+	OP_ADD
+	OP_SUBTRACT
+	OP_MULTIPLY
+	OP_DIVIDE
+	OP_NOT
+	OP_NEGATE
+
+	// OP_JUMP and OP_JUMP_IF_FALSE jump u16 bytes forward, OP_LOOP jumps u16 bytes back.
+	// OP_JUMP_IF_FALSE leaves the condition on the stack.
+	OP_JUMP
+	OP_JUMP_IF_FALSE
+	OP_LOOP
+
+	// OP_CALL calls the value below its u8 arguments.
+	// OP_TAIL_CALL does too, a closure it calls replaces the frame of the caller.
+	OP_CALL
+	OP_TAIL_CALL
+	// OP_CLOSURE makes a closure of the function constant u16,
+	// followed by an (isLocal, index) byte pair for each upvalue it captures
+	OP_CLOSURE
+	OP_CLOSE_UPVALUE
+	OP_RETURN
+
+	// OP_CLASS pushes a new class named by a constant
+	OP_CLASS
+	// OP_INHERIT pops the class below the superclass and makes it inherit from it
+	OP_INHERIT
+	// OP_METHOD and OP_STATIC_METHOD pop a closure into the class below it, named by a constant
+	OP_METHOD
+	OP_STATIC_METHOD
+
+	// OP_LIST, OP_MAP and OP_INTERPOLATE pop u16 elements, key value pairs or string parts
+	OP_LIST
+	OP_MAP
+	OP_INTERPOLATE
+
+	// OP_ITERATOR replaces the top value with an iterator over it.
+	// OP_FOR_NEXT pushes the next value of the iterator on top, or jumps u16 bytes forward once it is done.
+	OP_ITERATOR
+	OP_FOR_NEXT
+
+	// OP_THROW throws the top value
+	OP_THROW
+	// OP_TRY installs a handler u16 bytes forward that catches lox errors, with the caught value pushed.
+	// OP_TRY_FINALLY installs one that catches anything, to rethrow it with OP_RETHROW once finally ran.
+	OP_TRY
+	OP_TRY_FINALLY
+	OP_POP_HANDLER
+	OP_RETHROW
+
+	// OP_IMPORT pushes the module at the path constant u16
+	OP_IMPORT
+)
+
+var opNames = [...]string{
+	OP_CONSTANT:      "OP_CONSTANT",
+	OP_NIL:           "OP_NIL",
+	OP_TRUE:          "OP_TRUE",
+	OP_FALSE:         "OP_FALSE",
+	OP_POP:           "OP_POP",
+	OP_GET_LOCAL:     "OP_GET_LOCAL",
+	OP_SET_LOCAL:     "OP_SET_LOCAL",
+	OP_GET_GLOBAL:    "OP_GET_GLOBAL",
+	OP_DEFINE_GLOBAL: "OP_DEFINE_GLOBAL",
+	OP_SET_GLOBAL:    "OP_SET_GLOBAL",
+	OP_GET_UPVALUE:   "OP_GET_UPVALUE",
+	OP_SET_UPVALUE:   "OP_SET_UPVALUE",
+	OP_GET_PROPERTY:  "OP_GET_PROPERTY",
+	OP_SET_PROPERTY:  "OP_SET_PROPERTY",
+	OP_GET_SUPER:     "OP_GET_SUPER",
+	OP_GET_INDEX:     "OP_GET_INDEX",
+	OP_SET_INDEX:     "OP_SET_INDEX",
+	OP_EQUAL:         "OP_EQUAL",
+	OP_NOT_EQUAL:     "OP_NOT_EQUAL",
+	OP_GREATER:       "OP_GREATER",
+	OP_GREATER_EQUAL: "OP_GREATER_EQUAL",
+	OP_LESS:          "OP_LESS",
+	OP_LESS_EQUAL:    "OP_LESS_EQUAL",
+	OP_ADD:           "OP_ADD",
+	OP_SUBTRACT:      "OP_SUBTRACT",
+	OP_MULTIPLY:      "OP_MULTIPLY",
+	OP_DIVIDE:        "OP_DIVIDE",
+	OP_NOT:           "OP_NOT",
+	OP_NEGATE:        "OP_NEGATE",
+	OP_JUMP:          "OP_JUMP",
+	OP_JUMP_IF_FALSE: "OP_JUMP_IF_FALSE",
+	OP_LOOP:          "OP_LOOP",
+	OP_CALL:          "OP_CALL",
+	OP_TAIL_CALL:     "OP_TAIL_CALL",
+	OP_CLOSURE:       "OP_CLOSURE",
+	OP_CLOSE_UPVALUE: "OP_CLOSE_UPVALUE",
+	OP_RETURN:        "OP_RETURN",
+	OP_CLASS:         "OP_CLASS",
+	OP_INHERIT:       "OP_INHERIT",
+	OP_METHOD:        "OP_METHOD",
+	OP_STATIC_METHOD: "OP_STATIC_METHOD",
+	OP_LIST:          "OP_LIST",
+	OP_MAP:           "OP_MAP",
+	OP_INTERPOLATE:   "OP_INTERPOLATE",
+	OP_ITERATOR:      "OP_ITERATOR",
+	OP_FOR_NEXT:      "OP_FOR_NEXT",
+	OP_THROW:         "OP_THROW",
+	OP_TRY:           "OP_TRY",
+	OP_TRY_FINALLY:   "OP_TRY_FINALLY",
+	OP_POP_HANDLER:   "OP_POP_HANDLER",
+	OP_RETHROW:       "OP_RETHROW",
+	OP_IMPORT:        "OP_IMPORT",
+}
+
+func (op OpCode) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return "OP_UNKNOWN"
+}
+
+// Chunk is a sequence of bytecode with the constants it refers to
+type Chunk struct {
+	code      []byte
+	constants []interface{}
+	// tokens are the source tokens of the code, refs holds the index of the one of every byte.
+	// Errors are located with them.
+	tokens []Token
+	refs   []int32
+	// indexes finds constants already in the table, numbers are keyed by their numberKey
+	indexes map[interface{}]int
+}
+
+// numberKey keys a number constant by its bits, 0 and -0 are equal but different constants
+type numberKey uint64
+
+func (c *Chunk) write(b byte, token Token) {
+	c.code = append(c.code, b)
+	// instructions and their operands share a token, so do the instructions of a single node
+	if n := len(c.tokens); n == 0 || c.tokens[n-1] != token {
+		c.tokens = append(c.tokens, token)
+	}
+	c.refs = append(c.refs, int32(len(c.tokens)-1))
+}
+
+// token is the source token of the byte at offset
+func (c *Chunk) token(offset int) Token {
+	return c.tokens[c.refs[offset]]
+}
+
+// addConstant returns the index of value in the constant table, adding it if needed
+func (c *Chunk) addConstant(value interface{}) int {
+	// functions are never shared, other constants are comparable values
+	if _, ok := value.(*FunctionProto); ok {
+		c.constants = append(c.constants, value)
+		return len(c.constants) - 1
+	}
+	key := value
+	if number, ok := value.(float64); ok {
+		key = numberKey(math.Float64bits(number))
+	}
+	if i, ok := c.indexes[key]; ok {
+		return i
+	}
+	if c.indexes == nil {
+		c.indexes = make(map[interface{}]int, 0)
+	}
+	c.constants = append(c.constants, value)
+	c.indexes[key] = len(c.constants) - 1
+	return len(c.constants) - 1
+}
+
+// FunctionProto is a compiled function, closures are made of it at runtime
+type FunctionProto struct {
+	name string
+	// className is the class a method belongs to, empty for plain functions
+	className    string
+	arity        int
+	upvalueCount int
+	// isInit marks class initializers, they always return this
+	isInit bool
+	chunk  Chunk
+}
+
+// frameName is how the function shows up in stack traces, like Function.name
+func (f *FunctionProto) frameName() string {
+	name := f.name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	if f.className != "" {
+		name = f.className + "." + name
+	}
+	return name
+}
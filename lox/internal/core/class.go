@@ -1,10 +1,16 @@
-package main
+package core
 
 type Object interface {
 	get(name Token) (interface{}, error)
 	set(name Token, value interface{}) error
 }
 
+// Indexable is implemented by values supporting `value[index]` and `value[index] = x`
+type Indexable interface {
+	getIndex(bracket Token, index interface{}) interface{}
+	setIndex(bracket Token, index interface{}, value interface{})
+}
+
 type Class struct {
 	name          string
 	super         *Class
@@ -23,9 +29,11 @@ func (c Class) call(interpreter Interpreter, args []interface{}) interface{} {
 		class:  c,
 		fields: make(map[string]interface{}, 0),
 	}
+	interpreter.lox.allocate(instanceSize, callSite(interpreter), interpreter.env)
 
 	// call constructor
 	if init, ok := c.findMethod("init"); ok {
+		interpreter.lox.allocate(envSize+functionSize, callSite(interpreter), interpreter.env)
 		init.bind(instance).call(interpreter, args)
 	}
 
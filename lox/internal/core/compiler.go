@@ -0,0 +1,870 @@
+package core
+
+import "fmt"
+
+// CompileError is a program the bytecode compiler can't encode, like a function with too many locals
+type CompileError struct {
+	token Token
+	msg   string
+}
+
+func (e CompileError) Error() string {
+	return fmt.Sprintf("[GLOX] CompileError: Line %d, Column %d, %s", e.token.line, e.token.column, e.msg)
+}
+
+// compilerLocal is a local variable, it lives in the stack slot of its index
+type compilerLocal struct {
+	name  string
+	depth int
+	// captured locals are closed over instead of popped when their scope ends
+	captured bool
+	// hidden locals are on the stack but can't be referred to by name,
+	// like the ones of a try block while its finally block is inlined
+	hidden bool
+}
+
+type compilerUpvalue struct {
+	index   byte
+	isLocal bool
+}
+
+// compilerLoop is a loop being compiled, break and continue jump out of it
+type compilerLoop struct {
+	label string
+	// locals and tries are how many locals and handlers there are outside of the loop body
+	locals    int
+	tries     int
+	breaks    []int
+	continues []int
+}
+
+// compilerTry is a handler installed by a try statement, finally is nil for catch handlers
+type compilerTry struct {
+	finally *BlockStmt
+	// locals is how many locals there were when the try statement began
+	locals int
+}
+
+/*
+Compiler turns the resolved AST of a module into bytecode for the VM, one Compiler per function.
+The resolver already reported every semantic error,
+so the only errors left are the limits of the bytecode format.
+*/
+type Compiler struct {
+	lox       *Lox
+	enclosing *Compiler
+	function  *FunctionProto
+	ftype     functionType
+	locals    []compilerLocal
+	upvalues  []compilerUpvalue
+	// scopeDepth is 0 at the top level of the module, where variables are globals
+	scopeDepth int
+	loops      []*compilerLoop
+	tries      []compilerTry
+	// last is the token of the latest instruction, for nodes without a token of their own
+	last Token
+}
+
+func newCompiler(lox *Lox, enclosing *Compiler, function *FunctionProto, ftype functionType) *Compiler {
+	c := &Compiler{
+		lox:       lox,
+		enclosing: enclosing,
+		function:  function,
+		ftype:     ftype,
+	}
+	if enclosing != nil {
+		c.last = enclosing.last
+	}
+
+	// slot 0 holds the callee, methods know it as this
+	name := ""
+	if ftype == METHOD || ftype == INITIALIZER {
+		name = "this"
+	}
+	c.locals = append(c.locals, compilerLocal{name: name})
+	return c
+}
+
+// compileBytecode compiles the statements of a module into the function running its top level code
+func (l *Lox) compileBytecode(stmts []Stmt) *FunctionProto {
+	c := newCompiler(l, nil, &FunctionProto{name: "<script>"}, NONE)
+	c.statements(stmts)
+	c.emitReturn()
+	return c.function
+}
+
+// compileEval is compileBytecode for Runtime.Eval, the script returns the value of value when it is not nil
+func (l *Lox) compileEval(stmts []Stmt, value Expr) *FunctionProto {
+	c := newCompiler(l, nil, &FunctionProto{name: "<script>"}, NONE)
+	c.statements(stmts)
+	if value == nil {
+		c.emitReturn()
+		return c.function
+	}
+	c.expression(value)
+	c.emitOp(OP_RETURN, c.last)
+	return c.function
+}
+
+func (c *Compiler) statements(stmts []Stmt) {
+	for _, stmt := range stmts {
+		stmt.accept(c)
+	}
+}
+
+func (c *Compiler) expression(expr Expr) {
+	expr.accept(c)
+}
+
+func (c *Compiler) error(token Token, msg string) {
+	c.lox.errorReporter.report(CompileError{token, msg})
+}
+
+/* emitting bytecode */
+
+func (c *Compiler) chunk() *Chunk {
+	return &c.function.chunk
+}
+
+func (c *Compiler) emit(token Token, bytes ...byte) {
+	c.last = token
+	for _, b := range bytes {
+		c.chunk().write(b, token)
+	}
+}
+
+func (c *Compiler) emitOp(op OpCode, token Token) {
+	c.emit(token, byte(op))
+}
+
+// emitShort emits op followed by a two bytes operand
+func (c *Compiler) emitShort(op OpCode, operand int, token Token) {
+	c.emit(token, byte(op), byte(operand>>8), byte(operand))
+}
+
+func (c *Compiler) makeConstant(value interface{}, token Token) int {
+	constant := c.chunk().addConstant(value)
+	if constant > 0xffff {
+		c.error(token, "Too many constants in one chunk")
+		return 0
+	}
+	return constant
+}
+
+func (c *Compiler) emitConstant(op OpCode, value interface{}, token Token) {
+	c.emitShort(op, c.makeConstant(value, token), token)
+}
+
+// emitJump emits a jump with a placeholder offset, patchJump fills it in once the target is known
+func (c *Compiler) emitJump(op OpCode, token Token) int {
+	c.emitShort(op, 0xffff, token)
+	return len(c.chunk().code) - 2
+}
+
+// patchJump makes the jump at offset land on the next instruction
+func (c *Compiler) patchJump(offset int) {
+	jump := len(c.chunk().code) - offset - 2
+	if jump > 0xffff {
+		c.error(c.chunk().token(offset), "Too much code to jump over")
+	}
+	c.chunk().code[offset] = byte(jump >> 8)
+	c.chunk().code[offset+1] = byte(jump)
+}
+
+func (c *Compiler) emitLoop(start int, token Token) {
+	jump := len(c.chunk().code) + 3 - start
+	if jump > 0xffff {
+		c.error(token, "Loop body too large")
+	}
+	c.emitShort(OP_LOOP, jump, token)
+}
+
+func (c *Compiler) emitReturn() {
+	if c.ftype == INITIALIZER {
+		c.emit(c.last, byte(OP_GET_LOCAL), 0)
+	} else {
+		c.emitOp(OP_NIL, c.last)
+	}
+	c.emitOp(OP_RETURN, c.last)
+}
+
+/* variables */
+
+func (c *Compiler) beginScope() {
+	c.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	c.scopeDepth--
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		c.popLocal(c.locals[len(c.locals)-1])
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+func (c *Compiler) popLocal(local compilerLocal) {
+	if local.captured {
+		c.emitOp(OP_CLOSE_UPVALUE, c.last)
+	} else {
+		c.emitOp(OP_POP, c.last)
+	}
+}
+
+// discardLocals pops the locals above count off the stack, they stay known to the compiler.
+// break and continue use it, the code after them still runs with the locals in place.
+func (c *Compiler) discardLocals(count int) {
+	for i := len(c.locals) - 1; i >= count; i-- {
+		c.popLocal(c.locals[i])
+	}
+}
+
+// addLocal makes the value on top of the stack a local
+func (c *Compiler) addLocal(name Token) {
+	if len(c.locals) > 0xff {
+		c.error(name, "Too many local variables in function")
+		return
+	}
+	c.locals = append(c.locals, compilerLocal{name: name.literal, depth: c.scopeDepth})
+}
+
+// defineVariable declares the variable holding the value on top of the stack
+func (c *Compiler) defineVariable(name Token) {
+	if c.scopeDepth > 0 {
+		c.addLocal(name)
+		return
+	}
+	c.emitConstant(OP_DEFINE_GLOBAL, name.literal, name)
+}
+
+func (c *Compiler) resolveLocal(name string) int {
+	for i := len(c.locals) - 1; i >= 0; i-- {
+		if c.locals[i].name == name && !c.locals[i].hidden {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveUpvalue finds name in the enclosing functions, capturing it on the way
+func (c *Compiler) resolveUpvalue(name string) int {
+	if c.enclosing == nil {
+		return -1
+	}
+	if local := c.enclosing.resolveLocal(name); local >= 0 {
+		c.enclosing.locals[local].captured = true
+		return c.addUpvalue(byte(local), true)
+	}
+	if upvalue := c.enclosing.resolveUpvalue(name); upvalue >= 0 {
+		return c.addUpvalue(byte(upvalue), false)
+	}
+	return -1
+}
+
+func (c *Compiler) addUpvalue(index byte, isLocal bool) int {
+	for i, upvalue := range c.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return i
+		}
+	}
+	if len(c.upvalues) > 0xff {
+		c.error(c.last, "Too many closure variables in function")
+		return 0
+	}
+	c.upvalues = append(c.upvalues, compilerUpvalue{index, isLocal})
+	return len(c.upvalues) - 1
+}
+
+func (c *Compiler) getVariable(name Token) {
+	if local := c.resolveLocal(name.literal); local >= 0 {
+		c.emit(name, byte(OP_GET_LOCAL), byte(local))
+	} else if upvalue := c.resolveUpvalue(name.literal); upvalue >= 0 {
+		c.emit(name, byte(OP_GET_UPVALUE), byte(upvalue))
+	} else {
+		c.emitConstant(OP_GET_GLOBAL, name.literal, name)
+	}
+}
+
+func (c *Compiler) setVariable(name Token) {
+	if local := c.resolveLocal(name.literal); local >= 0 {
+		c.emit(name, byte(OP_SET_LOCAL), byte(local))
+	} else if upvalue := c.resolveUpvalue(name.literal); upvalue >= 0 {
+		c.emit(name, byte(OP_SET_UPVALUE), byte(upvalue))
+	} else {
+		c.emitConstant(OP_SET_GLOBAL, name.literal, name)
+	}
+}
+
+// syntheticToken names a variable the compiler introduces, located at token
+func syntheticToken(token Token, name string) Token {
+	token.literal = name
+	token.lexeme = name
+	return token
+}
+
+/* functions */
+
+// compileFunction emits a closure of stmt
+func (c *Compiler) compileFunction(stmt FunStmt, ftype functionType, className string) {
+	function := &FunctionProto{
+		name:      stmt.name.literal,
+		className: className,
+		arity:     len(stmt.params),
+		isInit:    ftype == INITIALIZER,
+	}
+	compiler := newCompiler(c.lox, c, function, ftype)
+	// params and the body share a scope, like the environment of Function.call
+	compiler.beginScope()
+	for _, param := range stmt.params {
+		compiler.addLocal(param)
+	}
+	compiler.statements(stmt.body.statements)
+	compiler.emitReturn()
+	function.upvalueCount = len(compiler.upvalues)
+
+	token := stmt.name
+	if token.tokentype == 0 && token.literal == "" {
+		token = compiler.last
+	}
+	c.emitConstant(OP_CLOSURE, function, token)
+	for _, upvalue := range compiler.upvalues {
+		isLocal := byte(0)
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		c.emit(token, isLocal, upvalue.index)
+	}
+}
+
+/* try statements and loops */
+
+// exitTries pops the handlers above count, running the finally blocks on the way out,
+// like the tree-walker's deferred finally blocks do when return, break or continue unwind them
+func (c *Compiler) exitTries(count int) {
+	tries := c.tries
+	for i := len(tries) - 1; i >= count; i-- {
+		c.emitOp(OP_POP_HANDLER, c.last)
+		if tries[i].finally == nil {
+			continue
+		}
+
+		// the finally block can't see the locals of the try block, nor be exited through the handlers it is outside of
+		hidden := make([]bool, len(c.locals))
+		for j := range c.locals {
+			hidden[j] = c.locals[j].hidden
+			if j >= tries[i].locals {
+				c.locals[j].hidden = true
+			}
+		}
+		c.tries = tries[:i]
+		c.visitBlockStmt(*tries[i].finally)
+		c.tries = tries
+		for j := range hidden {
+			c.locals[j].hidden = hidden[j]
+		}
+	}
+}
+
+// targetLoop finds the loop break or continue aims at, the resolver made sure there is one
+func (c *Compiler) targetLoop(label Token) *compilerLoop {
+	for i := len(c.loops) - 1; i >= 0; i-- {
+		if label.literal == "" || c.loops[i].label == label.literal {
+			return c.loops[i]
+		}
+	}
+	return &compilerLoop{}
+}
+
+func (c *Compiler) beginLoop(label Token) *compilerLoop {
+	loop := &compilerLoop{label: label.literal, locals: len(c.locals), tries: len(c.tries)}
+	c.loops = append(c.loops, loop)
+	return loop
+}
+
+func (c *Compiler) endLoop() {
+	c.loops = c.loops[:len(c.loops)-1]
+}
+
+/* statements */
+
+func (c *Compiler) visitExpressionStmt(stmt ExpressionStmt) {
+	c.expression(stmt.expression)
+	c.emitOp(OP_POP, c.last)
+}
+
+func (c *Compiler) visitFunStmt(stmt FunStmt) {
+	// a local function is declared before its body is compiled, so it can call itself
+	if c.scopeDepth > 0 {
+		c.addLocal(stmt.name)
+		c.compileFunction(stmt, FUNCTION, "")
+		return
+	}
+	c.compileFunction(stmt, FUNCTION, "")
+	c.defineVariable(stmt.name)
+}
+
+func (c *Compiler) visitReturnStmt(stmt ReturnStmt) {
+	c.last = stmt.keyword
+	// a call in tail position takes over the frame, unless a finally block or a catch has to run after it.
+	// OP_TAIL_CALL only falls through to the OP_RETURN after it for natives and classes.
+	if call, ok := stmt.value.(CallExpr); ok && len(c.tries) == 0 {
+		c.call(call, OP_TAIL_CALL)
+		c.emitOp(OP_RETURN, stmt.keyword)
+		return
+	}
+	if stmt.value != nil {
+		c.expression(stmt.value)
+	} else if c.ftype == INITIALIZER {
+		c.emit(stmt.keyword, byte(OP_GET_LOCAL), 0)
+	} else {
+		c.emitOp(OP_NIL, stmt.keyword)
+	}
+
+	if len(c.tries) > 0 {
+		// finally blocks run before returning, the value waits in a local of its own meanwhile
+		c.beginScope()
+		c.addLocal(syntheticToken(stmt.keyword, ""))
+		c.exitTries(0)
+		c.emit(stmt.keyword, byte(OP_GET_LOCAL), byte(len(c.locals)-1))
+		// nothing after the return runs, so there is nothing to pop
+		c.locals = c.locals[:len(c.locals)-1]
+		c.scopeDepth--
+	}
+	c.emitOp(OP_RETURN, stmt.keyword)
+}
+
+func (c *Compiler) visitVarStmt(stmt VarStmt) {
+	// literals have no token of their own, they are located by the statement they start
+	c.last = stmt.name
+	if stmt.init != nil {
+		c.expression(stmt.init)
+	} else {
+		c.emitOp(OP_NIL, stmt.name)
+	}
+	c.defineVariable(stmt.name)
+}
+
+func (c *Compiler) visitBlockStmt(stmt BlockStmt) {
+	c.beginScope()
+	c.statements(stmt.statements)
+	c.endScope()
+}
+
+func (c *Compiler) visitIfStmt(stmt IfStmt) {
+	c.expression(stmt.condition)
+	elseJump := c.emitJump(OP_JUMP_IF_FALSE, c.last)
+	c.emitOp(OP_POP, c.last)
+	stmt.consequent.accept(c)
+	endJump := c.emitJump(OP_JUMP, c.last)
+
+	c.patchJump(elseJump)
+	c.emitOp(OP_POP, c.last)
+	if stmt.alternate != nil {
+		stmt.alternate.accept(c)
+	}
+	c.patchJump(endJump)
+}
+
+func (c *Compiler) visitWhileStmt(stmt WhileStmt) {
+	start := len(c.chunk().code)
+	c.expression(stmt.condition)
+	exitJump := c.emitJump(OP_JUMP_IF_FALSE, c.last)
+	c.emitOp(OP_POP, c.last)
+
+	loop := c.beginLoop(stmt.label)
+	stmt.body.accept(c)
+	c.endLoop()
+
+	// continue lands on the increment of desugared for loops
+	for _, jump := range loop.continues {
+		c.patchJump(jump)
+	}
+	if stmt.increment != nil {
+		c.expression(stmt.increment)
+		c.emitOp(OP_POP, c.last)
+	}
+	c.emitLoop(start, c.last)
+
+	c.patchJump(exitJump)
+	c.emitOp(OP_POP, c.last)
+	for _, jump := range loop.breaks {
+		c.patchJump(jump)
+	}
+}
+
+func (c *Compiler) visitForInStmt(stmt ForInStmt) {
+	c.beginScope()
+	c.expression(stmt.iterable)
+	c.emitOp(OP_ITERATOR, stmt.keyword)
+	// the iterator stays on the stack for the whole loop
+	c.addLocal(syntheticToken(stmt.keyword, ""))
+
+	start := len(c.chunk().code)
+	exitJump := c.emitJump(OP_FOR_NEXT, stmt.keyword)
+
+	loop := c.beginLoop(stmt.label)
+	// the loop variable is popped, or closed over, at the end of every iteration,
+	// so closures capture each value separately
+	c.beginScope()
+	c.addLocal(stmt.name)
+	stmt.body.accept(c)
+	c.endScope()
+	c.endLoop()
+
+	for _, jump := range loop.continues {
+		c.patchJump(jump)
+	}
+	c.emitLoop(start, stmt.keyword)
+
+	c.patchJump(exitJump)
+	for _, jump := range loop.breaks {
+		c.patchJump(jump)
+	}
+	c.endScope()
+}
+
+func (c *Compiler) visitBreakStmt(stmt BreakStmt) {
+	loop := c.targetLoop(stmt.label)
+	c.exitTries(loop.tries)
+	c.discardLocals(loop.locals)
+	loop.breaks = append(loop.breaks, c.emitJump(OP_JUMP, stmt.keyword))
+}
+
+func (c *Compiler) visitContinueStmt(stmt ContinueStmt) {
+	loop := c.targetLoop(stmt.label)
+	c.exitTries(loop.tries)
+	c.discardLocals(loop.locals)
+	loop.continues = append(loop.continues, c.emitJump(OP_JUMP, stmt.keyword))
+}
+
+func (c *Compiler) visitThrowStmt(stmt ThrowStmt) {
+	c.last = stmt.keyword
+	c.expression(stmt.value)
+	c.emitOp(OP_THROW, stmt.keyword)
+}
+
+/*
+visitTryStmt compiles `try B catch (e) C finally F` like `try { try B catch (e) C } finally F`:
+
+	OP_TRY_FINALLY  → finally
+	OP_TRY          → catch
+	B
+	OP_POP_HANDLER
+	OP_JUMP         → end of catch
+
+catch:
+
+	C               // with the caught value as e
+	OP_POP_HANDLER
+	F
+	OP_JUMP         → end
+
+finally:
+
+	F               // with whatever unwound kept in a hidden local
+	OP_RETHROW
+
+end:
+*/
+func (c *Compiler) visitTryStmt(stmt TryStmt) {
+	var finallyJump int
+	if stmt.finallyBody != nil {
+		finallyJump = c.emitJump(OP_TRY_FINALLY, c.last)
+		c.tries = append(c.tries, compilerTry{stmt.finallyBody, len(c.locals)})
+	}
+
+	if stmt.catchBody != nil {
+		catchJump := c.emitJump(OP_TRY, c.last)
+		c.tries = append(c.tries, compilerTry{nil, len(c.locals)})
+		c.visitBlockStmt(stmt.body)
+		c.tries = c.tries[:len(c.tries)-1]
+		c.emitOp(OP_POP_HANDLER, c.last)
+		endJump := c.emitJump(OP_JUMP, c.last)
+
+		// the handler is gone once it caught something, the caught value is the error variable
+		c.patchJump(catchJump)
+		c.beginScope()
+		c.addLocal(stmt.name)
+		c.statements(stmt.catchBody.statements)
+		c.endScope()
+		c.patchJump(endJump)
+	} else {
+		c.visitBlockStmt(stmt.body)
+	}
+
+	if stmt.finallyBody != nil {
+		c.tries = c.tries[:len(c.tries)-1]
+		c.emitOp(OP_POP_HANDLER, c.last)
+		c.visitBlockStmt(*stmt.finallyBody)
+		endJump := c.emitJump(OP_JUMP, c.last)
+
+		c.patchJump(finallyJump)
+		c.beginScope()
+		c.addLocal(syntheticToken(c.last, ""))
+		c.visitBlockStmt(*stmt.finallyBody)
+		c.emitOp(OP_RETHROW, c.last)
+		// OP_RETHROW never falls through
+		c.locals = c.locals[:len(c.locals)-1]
+		c.scopeDepth--
+		c.patchJump(endJump)
+	}
+}
+
+func (c *Compiler) visitImportStmt(stmt ImportStmt) {
+	c.emitConstant(OP_IMPORT, stmt.path.lexeme.(string), stmt.path)
+	c.defineVariable(stmt.name)
+}
+
+func (c *Compiler) visitExportStmt(stmt ExportStmt) {
+	// like the tree-walker, the module collects its exports from the AST
+	stmt.declaration.accept(c)
+}
+
+func (c *Compiler) visitClassStmt(stmt ClassStmt) {
+	c.emitConstant(OP_CLASS, stmt.name.literal, stmt.name)
+	c.defineVariable(stmt.name)
+
+	// no super in static methods
+	if len(stmt.staticMethods) > 0 {
+		c.getVariable(stmt.name)
+		for _, fun := range stmt.staticMethods {
+			c.compileFunction(fun, FUNCTION, stmt.name.literal)
+			c.emitConstant(OP_STATIC_METHOD, fun.name.literal, fun.name)
+		}
+		c.emitOp(OP_POP, c.last)
+	}
+
+	// super is a local the methods close over, like the environment the tree-walker adds for it
+	if stmt.super != nil {
+		c.visitIdentifierExpr(*stmt.super)
+		c.beginScope()
+		c.addLocal(syntheticToken(stmt.super.name, "super"))
+		c.getVariable(stmt.name)
+		c.emitOp(OP_INHERIT, stmt.super.name)
+	}
+
+	c.getVariable(stmt.name)
+	for _, fun := range stmt.methods {
+		ftype := METHOD
+		if fun.name.literal == "init" {
+			ftype = INITIALIZER
+		}
+		c.compileFunction(fun, ftype, stmt.name.literal)
+		c.emitConstant(OP_METHOD, fun.name.literal, fun.name)
+	}
+	c.emitOp(OP_POP, c.last)
+
+	if stmt.super != nil {
+		c.endScope()
+	}
+}
+
+/* expressions, every one of them leaves its value on the stack */
+
+func (c *Compiler) visitBinaryExpr(expr BinaryExpr) interface{} {
+	c.expression(expr.left)
+	c.expression(expr.right)
+
+	switch expr.operator.tokentype {
+	case EQUAL_EQUAL:
+		c.emitOp(OP_EQUAL, expr.operator)
+	case BANG_EQUAL:
+		c.emitOp(OP_NOT_EQUAL, expr.operator)
+	case GREATER:
+		c.emitOp(OP_GREATER, expr.operator)
+	case GREATER_EQUAL:
+		c.emitOp(OP_GREATER_EQUAL, expr.operator)
+	case LESS:
+		c.emitOp(OP_LESS, expr.operator)
+	case LESS_EQUAL:
+		c.emitOp(OP_LESS_EQUAL, expr.operator)
+	case PLUS:
+		c.emitOp(OP_ADD, expr.operator)
+	case MINUS:
+		c.emitOp(OP_SUBTRACT, expr.operator)
+	case STAR:
+		c.emitOp(OP_MULTIPLY, expr.operator)
+	case SLASH:
+		c.emitOp(OP_DIVIDE, expr.operator)
+	default:
+		// binary gives nil for operators it does not know
+		c.emitOp(OP_POP, expr.operator)
+		c.emitOp(OP_POP, expr.operator)
+		c.emitOp(OP_NIL, expr.operator)
+	}
+	return nil
+}
+
+func (c *Compiler) visitLogicalExpr(expr LogicalExpr) interface{} {
+	c.expression(expr.left)
+	if expr.operator.tokentype == AND {
+		endJump := c.emitJump(OP_JUMP_IF_FALSE, expr.operator)
+		c.emitOp(OP_POP, expr.operator)
+		c.expression(expr.right)
+		c.patchJump(endJump)
+		return nil
+	}
+
+	elseJump := c.emitJump(OP_JUMP_IF_FALSE, expr.operator)
+	endJump := c.emitJump(OP_JUMP, expr.operator)
+	c.patchJump(elseJump)
+	c.emitOp(OP_POP, expr.operator)
+	c.expression(expr.right)
+	c.patchJump(endJump)
+	return nil
+}
+
+func (c *Compiler) visitGroupingExpr(expr GroupingExpr) interface{} {
+	c.expression(expr.expression)
+	return nil
+}
+
+func (c *Compiler) visitLiteralExpr(expr LiteralExpr) interface{} {
+	switch expr.value {
+	case nil:
+		c.emitOp(OP_NIL, c.last)
+	case true:
+		c.emitOp(OP_TRUE, c.last)
+	case false:
+		c.emitOp(OP_FALSE, c.last)
+	default:
+		c.emitConstant(OP_CONSTANT, expr.value, c.last)
+	}
+	return nil
+}
+
+func (c *Compiler) visitUnaryExpr(expr UnaryExpr) interface{} {
+	c.expression(expr.right)
+	switch expr.operator.tokentype {
+	case MINUS:
+		c.emitOp(OP_NEGATE, expr.operator)
+	case BANG:
+		c.emitOp(OP_NOT, expr.operator)
+	}
+	return nil
+}
+
+func (c *Compiler) visitSequenceExpr(expr SequenceExpr) interface{} {
+	// the sequence is worth its last expression, the tree-walker evaluates only that one
+	c.expression(expr.exprs[len(expr.exprs)-1])
+	return nil
+}
+
+func (c *Compiler) visitConditionExpr(expr ConditionExpr) interface{} {
+	c.expression(expr.test)
+	elseJump := c.emitJump(OP_JUMP_IF_FALSE, c.last)
+	c.emitOp(OP_POP, c.last)
+	c.expression(expr.consequent)
+	endJump := c.emitJump(OP_JUMP, c.last)
+	c.patchJump(elseJump)
+	c.emitOp(OP_POP, c.last)
+	c.expression(expr.alternate)
+	c.patchJump(endJump)
+	return nil
+}
+
+func (c *Compiler) visitAssignExpr(expr AssignExpr) interface{} {
+	if expr.right != nil {
+		c.expression(expr.right)
+	} else {
+		c.emitOp(OP_NIL, expr.left)
+	}
+	c.setVariable(expr.left)
+	return nil
+}
+
+func (c *Compiler) visitIdentifierExpr(expr IdentifierExpr) interface{} {
+	c.getVariable(expr.name)
+	return nil
+}
+
+func (c *Compiler) visitCallExpr(expr CallExpr) interface{} {
+	c.call(expr, OP_CALL)
+	return nil
+}
+
+func (c *Compiler) call(expr CallExpr, op OpCode) {
+	c.expression(expr.callee)
+	for _, argument := range expr.arguments {
+		c.expression(argument)
+	}
+	if len(expr.arguments) > 0xff {
+		c.error(expr.paren, "Too many arguments in call")
+	}
+	c.emit(expr.paren, byte(op), byte(len(expr.arguments)))
+}
+
+func (c *Compiler) visitFunExpr(expr FunExpr) interface{} {
+	c.compileFunction(FunStmt{Token{}, expr.params, expr.body, ""}, FUNCTION, "")
+	return nil
+}
+
+func (c *Compiler) visitSetExpr(expr SetExpr) interface{} {
+	c.expression(expr.object)
+	c.expression(expr.value)
+	c.emitConstant(OP_SET_PROPERTY, expr.name.literal, expr.name)
+	return nil
+}
+
+func (c *Compiler) visitGetExpr(expr GetExpr) interface{} {
+	c.expression(expr.object)
+	c.emitConstant(OP_GET_PROPERTY, expr.name.literal, expr.name)
+	return nil
+}
+
+func (c *Compiler) visitThisExpr(expr ThisExpr) interface{} {
+	c.getVariable(expr.keyword)
+	return nil
+}
+
+func (c *Compiler) visitSuperExpr(expr SuperExpr) interface{} {
+	c.getVariable(syntheticToken(expr.keyword, "super"))
+	c.getVariable(syntheticToken(expr.keyword, "this"))
+	c.emitConstant(OP_GET_SUPER, expr.method.literal, expr.method)
+	return nil
+}
+
+func (c *Compiler) visitInterpolationExpr(expr InterpolationExpr) interface{} {
+	for _, part := range expr.parts {
+		c.expression(part)
+	}
+	c.emitShort(OP_INTERPOLATE, len(expr.parts), c.last)
+	return nil
+}
+
+func (c *Compiler) visitListExpr(expr ListExpr) interface{} {
+	for _, element := range expr.elements {
+		c.expression(element)
+	}
+	if len(expr.elements) > 0xffff {
+		c.error(expr.bracket, "Too many elements in list literal")
+	}
+	c.emitShort(OP_LIST, len(expr.elements), expr.bracket)
+	return nil
+}
+
+func (c *Compiler) visitIndexExpr(expr IndexExpr) interface{} {
+	c.expression(expr.object)
+	c.expression(expr.index)
+	c.emitOp(OP_GET_INDEX, expr.bracket)
+	return nil
+}
+
+func (c *Compiler) visitIndexSetExpr(expr IndexSetExpr) interface{} {
+	c.expression(expr.object)
+	c.expression(expr.index)
+	c.expression(expr.value)
+	c.emitOp(OP_SET_INDEX, expr.bracket)
+	return nil
+}
+
+func (c *Compiler) visitMapExpr(expr MapExpr) interface{} {
+	for i, key := range expr.keys {
+		c.expression(key)
+		c.expression(expr.values[i])
+	}
+	if len(expr.keys) > 0xffff {
+		c.error(expr.brace, "Too many entries in map literal")
+	}
+	c.emitShort(OP_MAP, len(expr.keys), expr.brace)
+	return nil
+}
@@ -0,0 +1,243 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Severity tells how bad a diagnostic is
+type Severity int
+
+const (
+	// SeverityError stops the program from running
+	SeverityError Severity = iota
+	// SeverityWarning is only informative
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic codes, one per phase that can produce errors
+const (
+	CodeLexical = "E100"
+	CodeSyntax  = "E200"
+	CodeResolve = "E300"
+	CodeRuntime = "E400"
+	CodeCompile = "E500"
+)
+
+// Span locates a diagnostic in the source, offset and length are in bytes
+type Span struct {
+	line   int
+	column int
+	offset int
+	length int
+}
+
+// Diagnostic is a single problem found while scanning, parsing, resolving or running
+type Diagnostic struct {
+	severity Severity
+	code     string
+	msg      string
+	span     Span
+	// trace is the stack traceback of a runtime error, outermost first
+	trace []TraceEntry
+}
+
+// TraceEntry is a function in a stack traceback and where it was executing
+type TraceEntry struct {
+	name string
+	span Span
+}
+
+// traceback turns the call stack of a failed program into trace entries,
+// each function is located by the call it was making, the innermost one by the error
+func traceback(frames []CallFrame, at Token) []TraceEntry {
+	trace := make([]TraceEntry, 0, len(frames)+1)
+	name := "<script>"
+	for _, frame := range frames {
+		// calls the host made have no lox caller to show
+		if frame.callSite.offset >= 0 {
+			trace = append(trace, TraceEntry{name, tokenSpan(frame.callSite)})
+		}
+		name = frame.name
+	}
+	return append(trace, TraceEntry{name, tokenSpan(at)})
+}
+
+// describe formats the entry the way tracebacks print it
+func (t TraceEntry) describe(lox *Lox) string {
+	if t.span.offset < 0 {
+		return "at " + t.name
+	}
+	return fmt.Sprintf("at %s (%s:%d:%d)", t.name, lox.fileAt(t.span.offset).name, t.span.line, t.span.column)
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s[%s]: Line %d, Column %d, %s", d.severity, d.code, d.span.line, d.span.column, d.msg)
+}
+
+func tokenSpan(token Token) Span {
+	return Span{token.line, token.column, token.offset, token.length}
+}
+
+// toDiagnostic converts the errors each phase produces
+func toDiagnostic(err error) Diagnostic {
+	switch e := err.(type) {
+	case TokenError:
+		return Diagnostic{SeverityError, CodeLexical, e.msg, Span{e.line, e.column, e.offset, e.length}, nil}
+	case ParseError:
+		return Diagnostic{SeverityError, CodeSyntax, e.msg, tokenSpan(e.token), nil}
+	case ResolveError:
+		return Diagnostic{SeverityError, CodeResolve, e.msg, tokenSpan(e.token), nil}
+	case RuntimeError:
+		return Diagnostic{SeverityError, CodeRuntime, e.msg, tokenSpan(e.token), nil}
+	case CompileError:
+		return Diagnostic{SeverityError, CodeCompile, e.msg, tokenSpan(e.token), nil}
+	}
+	return Diagnostic{SeverityError, CodeRuntime, err.Error(), Span{offset: -1}, nil}
+}
+
+// you will likely have multiple ways errors get displayed
+// on stderr, in an IDE’s error window, logged to a file, etc.
+// ErrorReporter collects diagnostics, so a single run can report all of them at the end
+type ErrorReporter struct {
+	lox         *Lox
+	diagnostics []Diagnostic
+}
+
+func (e *ErrorReporter) report(err error) {
+	diagnostic := toDiagnostic(err)
+	if diagnostic.severity == SeverityError {
+		if _, ok := err.(RuntimeError); ok {
+			e.lox.hadRuntimeError = true
+		} else {
+			e.lox.hasError = true
+		}
+	}
+	e.diagnostics = append(e.diagnostics, diagnostic)
+}
+
+// reportUncaught reports an error that unwound the whole program, along with its traceback
+func (e *ErrorReporter) reportUncaught(err RuntimeError, trace []TraceEntry) {
+	e.report(err)
+	// a trace with only the script in it says nothing the error does not
+	if len(trace) > 1 {
+		e.diagnostics[len(e.diagnostics)-1].trace = trace
+	}
+}
+
+// flush prints every collected diagnostic and forgets them
+func (e *ErrorReporter) flush(out io.Writer) {
+	file, ok := out.(*os.File)
+	style := newStyle(ok && isTerminal(file))
+	for _, diagnostic := range e.diagnostics {
+		e.render(out, diagnostic, style)
+	}
+	e.diagnostics = e.diagnostics[:0]
+}
+
+// render shows a diagnostic the way modern compilers do:
+//
+//	error[E200]: Unexpected end of input, Expect ';' after value
+//	 --> index.lox:3:9
+//	  |
+//	3 | var a = 1
+//	  |         ^
+func (e *ErrorReporter) render(out io.Writer, d Diagnostic, s style) {
+	color := s.red
+	if d.severity == SeverityWarning {
+		color = s.yellow
+	}
+	fmt.Fprintf(out, "%s%s%s[%s]%s: %s%s%s\n", s.bold, color, d.severity, d.code, s.reset, s.bold, d.msg, s.reset)
+
+	if d.span.offset < 0 {
+		return
+	}
+	file := e.lox.fileAt(d.span.offset)
+	gutter := strings.Repeat(" ", len(fmt.Sprint(d.span.line)))
+	fmt.Fprintf(out, "%s%s-->%s %s:%d:%d\n", gutter, s.blue, s.reset, file.name, d.span.line, d.span.column)
+
+	quote(out, d, file, gutter, color, s)
+
+	if len(d.trace) > 0 {
+		fmt.Fprintf(out, "%sstack traceback (most recent call last):%s\n", s.bold, s.reset)
+		for _, entry := range d.trace {
+			fmt.Fprintf(out, "  %s\n", entry.describe(e.lox))
+		}
+	}
+}
+
+// quote prints the source line of the diagnostic with its span underlined, the span is relative to file
+func quote(out io.Writer, d Diagnostic, file sourceFile, gutter string, color string, s style) {
+	source := file.src
+	offset := d.span.offset - file.offset
+	if offset > len(source) || offset+d.span.length > len(source)+1 {
+		// the source was released
+		return
+	}
+	lineStart, lineEnd := lineBounds(source, offset)
+	line := source[lineStart:lineEnd]
+
+	fmt.Fprintf(out, "%s %s|%s\n", gutter, s.blue, s.reset)
+	fmt.Fprintf(out, "%s%d |%s %s\n", s.blue, d.span.line, s.reset, line)
+
+	// keep tabs so the underline lines up with the source above it
+	var padding strings.Builder
+	for _, c := range source[lineStart:offset] {
+		if c == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteRune(' ')
+		}
+	}
+	// a span running past the end of the line is cut there
+	end := offset + d.span.length
+	if end > lineEnd {
+		end = lineEnd
+	}
+	width := len([]rune(source[offset:end]))
+	underline := "^"
+	if width > 1 {
+		underline += strings.Repeat("~", width-1)
+	}
+	fmt.Fprintf(out, "%s %s|%s %s%s%s%s\n", gutter, s.blue, s.reset, padding.String(), color, underline, s.reset)
+}
+
+// lineBounds finds the whole line the byte at offset is on
+func lineBounds(source string, offset int) (start int, end int) {
+	start = strings.LastIndex(source[:offset], "\n") + 1
+	end = strings.Index(source[offset:], "\n")
+	if end < 0 {
+		return start, len(source)
+	}
+	return start, end + offset
+}
+
+// style holds the ANSI escape codes used when rendering, all empty without color
+type style struct {
+	bold, red, yellow, blue, reset string
+}
+
+func newStyle(color bool) style {
+	if !color {
+		return style{}
+	}
+	return style{"\x1b[1m", "\x1b[31m", "\x1b[33m", "\x1b[34m", "\x1b[0m"}
+}
+
+// isTerminal reports whether file is a terminal, honoring the NO_COLOR convention
+func isTerminal(file *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// compileDiagnostics compiles src and returns what was reported, in order
+func compileDiagnostics(src string) []Diagnostic {
+	runtime, _ := NewRuntime(Options{})
+	runtime.lox.compile("test.lox", src)
+	return runtime.lox.errorReporter.diagnostics
+}
+
+func TestDiagnosticsAreCollected(t *testing.T) {
+	src := "print(\"ran\");\n{ var a = a; }\nreturn 1;\nthis;"
+	diagnostics := compileDiagnostics(src)
+	if len(diagnostics) != 3 {
+		t.Fatalf("reported %v", diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.code != CodeResolve || d.span.line != i+2 {
+			t.Errorf("diagnostic %d is %v", i, d)
+		}
+	}
+
+	// all of them are rendered, the first one is returned and nothing runs
+	var out, stderr bytes.Buffer
+	runtime, _ := NewRuntime(Options{Stdout: &out, Stderr: &stderr})
+	_, err := runtime.Eval(src)
+	if lerr, ok := err.(*Error); !ok || lerr.Line != 2 {
+		t.Errorf("Eval failed with %v", err)
+	}
+	if n := strings.Count(stderr.String(), "error[E300]"); n != 3 {
+		t.Errorf("%d errors were rendered:\n%s", n, stderr.String())
+	}
+	if out.Len() > 0 {
+		t.Errorf("a program that does not compile printed %q", out.String())
+	}
+}
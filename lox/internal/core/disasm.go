@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// disassembleFile prints the bytecode the VM would run for the script at path, see `glox disasm`
+func (l *Lox) disassembleFile(out io.Writer, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	l.filename = path
+
+	function, _ := l.compileFile(path, string(content))
+	if !l.hasError {
+		l.disassemble(out, function)
+	}
+	return l.takeError()
+}
+
+// disassemble lists function and then every function it makes, one instruction a line:
+//
+//	== <script> ==
+//	             ; var a = 1;
+//	0000    1 OP_CONSTANT           0 1
+//	0003    | OP_DEFINE_GLOBAL      1 "a"
+func (l *Lox) disassemble(out io.Writer, function *FunctionProto) {
+	fmt.Fprintf(out, "== %s ==\n", function.frameName())
+
+	chunk := &function.chunk
+	line := -1
+	for offset := 0; offset < len(chunk.code); {
+		token := chunk.token(offset)
+		if token.line != line {
+			line = token.line
+			fmt.Fprintf(out, "%13s; %s\n", "", l.sourceLine(token))
+			fmt.Fprintf(out, "%04d %4d ", offset, line)
+		} else {
+			fmt.Fprintf(out, "%04d %4s ", offset, "|")
+		}
+		offset = l.disassembleInstruction(out, chunk, offset)
+	}
+
+	for _, constant := range chunk.constants {
+		if nested, ok := constant.(*FunctionProto); ok {
+			fmt.Fprintln(out)
+			l.disassemble(out, nested)
+		}
+	}
+}
+
+// disassembleInstruction prints the instruction at offset with its operands, and returns where the next one starts
+func (l *Lox) disassembleInstruction(out io.Writer, chunk *Chunk, offset int) int {
+	op := OpCode(chunk.code[offset])
+	short := func(at int) int {
+		return int(chunk.code[at])<<8 | int(chunk.code[at+1])
+	}
+
+	switch op {
+	case OP_CONSTANT, OP_GET_GLOBAL, OP_DEFINE_GLOBAL, OP_SET_GLOBAL,
+		OP_GET_PROPERTY, OP_SET_PROPERTY, OP_GET_SUPER,
+		OP_CLASS, OP_METHOD, OP_STATIC_METHOD, OP_IMPORT:
+		index := short(offset + 1)
+		fmt.Fprintf(out, "%-18s %4d %s\n", op, index, constantString(chunk.constants[index]))
+		return offset + 3
+
+	case OP_GET_LOCAL, OP_SET_LOCAL, OP_GET_UPVALUE, OP_SET_UPVALUE, OP_CALL, OP_TAIL_CALL:
+		fmt.Fprintf(out, "%-18s %4d\n", op, chunk.code[offset+1])
+		return offset + 2
+
+	case OP_LIST, OP_MAP, OP_INTERPOLATE:
+		fmt.Fprintf(out, "%-18s %4d\n", op, short(offset+1))
+		return offset + 3
+
+	case OP_JUMP, OP_JUMP_IF_FALSE, OP_FOR_NEXT, OP_TRY, OP_TRY_FINALLY:
+		fmt.Fprintf(out, "%-18s %4d -> %04d\n", op, offset, offset+3+short(offset+1))
+		return offset + 3
+
+	case OP_LOOP:
+		fmt.Fprintf(out, "%-18s %4d -> %04d\n", op, offset, offset+3-short(offset+1))
+		return offset + 3
+
+	case OP_CLOSURE:
+		index := short(offset + 1)
+		function := chunk.constants[index].(*FunctionProto)
+		fmt.Fprintf(out, "%-18s %4d %s\n", op, index, constantString(function))
+		offset += 3
+		for i := 0; i < function.upvalueCount; i++ {
+			kind := "upvalue"
+			if chunk.code[offset] == 1 {
+				kind = "local"
+			}
+			fmt.Fprintf(out, "%04d    |   %-24s %s %d\n", offset, "", kind, chunk.code[offset+1])
+			offset += 2
+		}
+		return offset
+	}
+
+	fmt.Fprintf(out, "%s\n", op)
+	return offset + 1
+}
+
+// sourceLine is the source line token is on, without its indentation
+func (l *Lox) sourceLine(token Token) string {
+	file := l.fileAt(token.offset)
+	offset := token.offset - file.offset
+	if token.offset < 0 || offset > len(file.src) {
+		return ""
+	}
+	start, end := lineBounds(file.src, offset)
+	return strings.TrimSpace(file.src[start:end])
+}
+
+// constantString shows a constant the way it would be written in lox
+func constantString(constant interface{}) string {
+	switch constant := constant.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(constant)
+	case *FunctionProto:
+		return "<fn " + constant.frameName() + ">"
+	}
+	return stringify(constant)
+}
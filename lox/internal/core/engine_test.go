@@ -0,0 +1,136 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// enginePrograms are run on both engines, which must print the same thing and fail the same way
+var enginePrograms = map[string]string{
+	"arithmetic": `
+		print(1 + 2 * 3 - 4 / 8);
+		print(10 / 0);
+		print(-10 / 0);
+		print("a" + 1, 2 + "b");
+		print(1 < 2, "a" >= "b", !nil, !0);
+		print(-0);
+		var z = 0;
+		print(1 / z, 1 / -0);
+	`,
+	"equality": `
+		class A {}
+		var a = A();
+		var b = A();
+		print(a == a, a == b, a != b);
+		print(A == A, A == a);
+		fun f() {}
+		fun g() {}
+		print(f == f, f == g, f != g);
+		var h = fun () {};
+		var i = fun () {};
+		print(h == h, h == i);
+		print([] == [], nil == false, 1 == "1", "x" == "x");
+	`,
+	"methods": `
+		class Point {
+			init(x, y) { this.x = x; this.y = y; }
+			sum() { return this.x + this.y; }
+		}
+		var p = Point(1, 2);
+		var sum = p.sum;
+		print(sum == sum, p.sum == p.sum);
+	`,
+	"closures": `
+		fun counter() {
+			var n = 0;
+			return fun () { n = n + 1; return n; };
+		}
+		var c = counter();
+		c();
+		print(c(), counter()());
+	`,
+	"classes": `
+		class Animal {
+			init(name) { this.name = name; }
+			speak() { return this.name + " makes a sound"; }
+		}
+		class Dog < Animal {
+			speak() { return super.speak() + ", woof"; }
+		}
+		print(Dog("rex").speak());
+		print(Dog, Dog("rex"));
+	`,
+	"collections": `
+		var xs = [3, 1, 2];
+		xs.push(4);
+		print(xs, xs.len(), xs.slice(1, 3));
+		print(xs.map(fun (x) { return x * 2; }).filter(fun (x) { return x > 4; }));
+		var m = { "a": 1, 2: "b" };
+		m["c"] = 3;
+		print(m, m.keys(), m.values(), m.has("a"));
+		for (var x in xs) { if (x == 2) break; print(x); }
+		for (var k in m) print(k);
+	`,
+	"strings": `
+		var name = "lox";
+		print("hello ${name}, ${1 + 2}", "tab\there");
+		for (var c in "abc") print(c);
+	`,
+	"errors": `
+		try {
+			throw Error("boom");
+		} catch (e) {
+			print(e);
+		} finally {
+			print("finally");
+		}
+		fun fail() { return [][1]; }
+		try { fail(); } catch (e) { print(e); }
+		print(nil + 1);
+	`,
+	"tail calls": `
+		fun loop(n, acc) {
+			if (n == 0) return acc;
+			return loop(n - 1, acc + n);
+		}
+		print(loop(100000, 0));
+	`,
+}
+
+func TestEnginesAgree(t *testing.T) {
+	for name, src := range enginePrograms {
+		interpreted, interpretedErr := runOn(false, src)
+		compiled, compiledErr := runOn(true, src)
+		if interpreted != compiled {
+			t.Errorf("%s: the interpreter printed\n%s\nthe VM printed\n%s", name, interpreted, compiled)
+		}
+		if fmt.Sprint(interpretedErr) != fmt.Sprint(compiledErr) {
+			t.Errorf("%s: the interpreter failed with %v, the VM with %v", name, interpretedErr, compiledErr)
+		}
+	}
+}
+
+// runOn runs src on the VM or on the interpreter, and returns what it printed and how it failed
+func runOn(vm bool, src string) (string, error) {
+	var out bytes.Buffer
+	runtime, _ := NewRuntime(Options{VM: vm, Stdout: &out})
+	_, err := runtime.Eval(src)
+	return out.String(), err
+}
+
+func TestAssignmentOrder(t *testing.T) {
+	// the assigned value is evaluated before the target is checked, on both engines
+	for _, src := range []string{`nil.x = print("value");`, `nil[print("index")] = print("value");`, `1[0] = print("value");`} {
+		interpreted, interpretedErr := runOn(false, src)
+		compiled, compiledErr := runOn(true, src)
+		if interpreted != compiled || fmt.Sprint(interpretedErr) != fmt.Sprint(compiledErr) {
+			t.Errorf("%s: the interpreter printed %q and failed with %v, the VM printed %q and failed with %v",
+				src, interpreted, interpretedErr, compiled, compiledErr)
+		}
+		if interpretedErr == nil || !strings.HasSuffix(interpreted, "value\n") {
+			t.Errorf("%s: printed %q and failed with %v", src, interpreted, interpretedErr)
+		}
+	}
+}
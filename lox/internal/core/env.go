@@ -0,0 +1,91 @@
+package core
+
+// env is a scope at runtime.
+// Locals live in slots, numbered by the resolver in the order they are declared,
+// globals are looked up by name since the resolver can't know all of them.
+type env struct {
+	// values holds the globals, only the outermost environment of a module has it
+	values map[string]interface{}
+	slots  []interface{}
+	parent *env
+}
+
+// newGlobals makes the outermost environment of a module
+func newGlobals() *env {
+	return &env{
+		values: make(map[string]interface{}, 0),
+	}
+}
+
+// newEnv makes a local scope inside parent
+func newEnv(parent *env) *env {
+	return &env{
+		slots:  make([]interface{}, 0, 4),
+		parent: parent,
+	}
+}
+
+// define declares a variable, a local takes the next slot, which is the one the resolver gave it
+func (e *env) define(name string, value interface{}) (slot int) {
+	if e.values != nil {
+		e.values[name] = value
+		return -1
+	}
+	e.slots = append(e.slots, value)
+	return len(e.slots) - 1
+}
+
+// redefine sets a variable define returned slot for
+func (e *env) redefine(name string, slot int, value interface{}) {
+	if slot < 0 {
+		e.values[name] = value
+	} else {
+		e.slots[slot] = value
+	}
+}
+
+// get looks up a global
+func (e *env) get(token Token) (interface{}, error) {
+	if value, ok := e.root().values[token.literal]; ok {
+		return value, nil
+	}
+
+	return nil, RuntimeError{token, "Undefined variable: " + token.literal}
+}
+
+// getAt reads the local the resolver found distance scopes up
+func (e *env) getAt(distance int, slot int) interface{} {
+	return e.ancestor(distance).slots[slot]
+}
+
+func (e *env) ancestor(distance int) *env {
+	environment := e
+	for i := 0; i < distance; i++ {
+		environment = environment.parent
+	}
+	return environment
+}
+
+// root is the outermost environment, the globals of the module e belongs to
+func (e *env) root() *env {
+	for e.parent != nil {
+		e = e.parent
+	}
+	return e
+}
+
+// assign sets a global
+func (e *env) assign(token Token, value interface{}) (interface{}, error) {
+	globals := e.root().values
+	if _, ok := globals[token.literal]; ok {
+		globals[token.literal] = value
+		return value, nil
+	}
+
+	return nil, RuntimeError{token, "Undefined variable: " + token.literal}
+}
+
+// assignAt sets the local the resolver found distance scopes up
+func (e *env) assignAt(distance int, slot int, value interface{}) {
+	e.ancestor(distance).slots[slot] = value
+}
@@ -0,0 +1,103 @@
+package core
+
+import "fmt"
+
+// ThrowSignal unwinds the go stack to the closest try statement, a panic RuntimeError shares with it
+type ThrowSignal struct {
+	// keyword is the throw statement, where an uncaught value is reported
+	keyword Token
+	value   interface{}
+}
+
+// ErrorObject is the value catch receives for runtime errors, scripts make their own with Error(message)
+type ErrorObject struct {
+	message string
+	token   Token
+	// trace is the stack traceback where the error happened, outermost first
+	trace []TraceEntry
+	lox   *Lox
+}
+
+func newErrorObject(interpreter Interpreter, err RuntimeError) *ErrorObject {
+	return &ErrorObject{
+		message: err.msg,
+		token:   err.token,
+		trace:   traceback(*interpreter.frames, err.token),
+		lox:     interpreter.lox,
+	}
+}
+
+// runtimeError is how the error is reported when nothing catches it
+func (e *ErrorObject) runtimeError() RuntimeError {
+	return RuntimeError{e.token, e.message}
+}
+
+func (e *ErrorObject) String() string {
+	return "Error: " + e.message
+}
+
+func (e *ErrorObject) get(name Token) (interface{}, error) {
+	switch name.literal {
+	case "message":
+		return e.message, nil
+	case "line":
+		return float64(e.token.line), nil
+	case "stack":
+		stack := make([]interface{}, len(e.trace))
+		for i, entry := range e.trace {
+			stack[i] = entry.describe(e.lox)
+		}
+		return NewList(stack), nil
+	}
+	return nil, RuntimeError{
+		name,
+		"Undefined property",
+	}
+}
+
+func (e *ErrorObject) set(name Token, value interface{}) error {
+	return RuntimeError{
+		name,
+		"Cannot set properties on an error",
+	}
+}
+
+// ErrorConstructor is the `Error(message)` native
+var ErrorConstructor = &NativeFunction{"Error", 1, func(interpreter Interpreter, args []interface{}) interface{} {
+	// the topmost frame is this very call, the error belongs to whoever made it
+	frames := *interpreter.frames
+	site := callSite(interpreter)
+	if len(frames) > 0 {
+		frames = frames[:len(frames)-1]
+	}
+
+	err := &ErrorObject{
+		message: stringify(args[0]),
+		token:   site,
+		trace:   traceback(frames, site),
+		lox:     interpreter.lox,
+	}
+	interpreter.lox.allocate(sizeOf(err), site, interpreter.env)
+	return err
+}}
+
+// caught turns what a try statement recovered into the value its catch clause receives,
+// ok is false for panics that are not lox errors, they keep unwinding
+func (v Interpreter) caught(err interface{}) (value interface{}, ok bool) {
+	switch err := err.(type) {
+	case ThrowSignal:
+		return err.value, true
+	case RuntimeError:
+		return newErrorObject(v, err), true
+	}
+	return nil, false
+}
+
+// uncaught is the error and traceback to report for a throw nothing caught
+func (s ThrowSignal) uncaught(frames []CallFrame) (RuntimeError, []TraceEntry) {
+	if err, ok := s.value.(*ErrorObject); ok {
+		return err.runtimeError(), err.trace
+	}
+	err := RuntimeError{s.keyword, fmt.Sprintf("Uncaught %s", stringify(s.value))}
+	return err, traceback(frames, s.keyword)
+}
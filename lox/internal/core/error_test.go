@@ -0,0 +1,44 @@
+package core
+
+import "testing"
+
+func TestTryCatchFinally(t *testing.T) {
+	expectPrints(t, `
+		try { throw "thrown"; } catch (e) { print(e); }
+		try { [][0]; } catch (e) { print(e.message, e.line); }
+		fun fails() { throw Error("deep"); }
+		try { fails(); print("skipped"); } catch (e) { print(e.message, e.stack.len()); } finally { print("finally"); }
+		try {
+			try { throw 1; } finally { print("inner finally"); }
+		} catch (e) { print("rethrown", e); }
+		try { try { throw 1; } catch (e) { throw e + 1; } } catch (e) { print(e); }
+	`, "thrown\nIndex 0 out of range [0, 0) 3\ndeep 2\nfinally\ninner finally\nrethrown 1\n2\n")
+
+	// break, continue and return leave through finally
+	expectPrints(t, `
+		for (var i = 0; i < 3; i = i + 1) {
+			try {
+				if (i == 0) continue;
+				if (i == 2) break;
+				print("body", i);
+			} finally {
+				print("finally", i);
+			}
+		}
+		fun f() {
+			try { return "returned"; } finally { print("finally f"); }
+		}
+		print(f());
+		outer: while (true) {
+			while (true) {
+				try { break outer; } finally { print("finally outer"); }
+			}
+		}
+	`, "finally 0\nbody 1\nfinally 1\nfinally 2\nfinally f\nreturned\nfinally outer\n")
+
+	for _, vm := range []bool{false, true} {
+		if _, err := runOn(vm, "\nthrow Error(\"up\");"); err == nil || err.(*Error).Message != "up" || err.(*Error).Line != 2 {
+			t.Errorf("vm %v: an uncaught throw failed with %v", vm, err)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package core
 
 type Expr interface {
 	accept(visitor Visitor) interface{}
@@ -135,6 +135,8 @@ func (s IdentifierExpr) accept(visitor Visitor) interface{} {
 }
 
 type FunExpr struct {
+	keyword Token
+
 	params []Token
 
 	body BlockStmt
@@ -153,3 +155,59 @@ type SuperExpr struct {
 func (s SuperExpr) accept(visitor Visitor) interface{} {
 	return visitor.visitSuperExpr(s)
 }
+
+type InterpolationExpr struct {
+	parts []Expr
+}
+
+func (s InterpolationExpr) accept(visitor Visitor) interface{} {
+	return visitor.visitInterpolationExpr(s)
+}
+
+type ListExpr struct {
+	bracket Token
+
+	elements []Expr
+}
+
+func (s ListExpr) accept(visitor Visitor) interface{} {
+	return visitor.visitListExpr(s)
+}
+
+type IndexExpr struct {
+	object Expr
+
+	bracket Token
+
+	index Expr
+}
+
+func (s IndexExpr) accept(visitor Visitor) interface{} {
+	return visitor.visitIndexExpr(s)
+}
+
+type IndexSetExpr struct {
+	object Expr
+
+	bracket Token
+
+	index Expr
+
+	value Expr
+}
+
+func (s IndexSetExpr) accept(visitor Visitor) interface{} {
+	return visitor.visitIndexSetExpr(s)
+}
+
+type MapExpr struct {
+	brace Token
+
+	keys []Expr
+
+	values []Expr
+}
+
+func (s MapExpr) accept(visitor Visitor) interface{} {
+	return visitor.visitMapExpr(s)
+}
@@ -0,0 +1,278 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	refType   = reflect.TypeOf(Ref{})
+)
+
+// goFunction makes a native of a go func. Lox arguments are converted to the types of its parameters,
+// a call with an argument that does not convert raises a runtime error.
+// The func returns nothing, a value, an error, or a value and an error,
+// a non nil error is raised as a runtime error with its message.
+func goFunction(name string, fn reflect.Value) (*NativeFunction, error) {
+	t := fn.Type()
+	results := t.NumOut()
+	if results > 0 && t.Out(results-1) == errorType {
+		results--
+	}
+	if results > 1 {
+		return nil, fmt.Errorf("lox: %s returns %s, natives return at most a value and an error", name, t)
+	}
+
+	arity := t.NumIn()
+	if t.IsVariadic() {
+		arity = -1
+	}
+	return &NativeFunction{name, arity, func(interpreter Interpreter, args []interface{}) interface{} {
+		site := callSite(interpreter)
+
+		in, err := goArguments(name, t, args)
+		if err != nil {
+			panic(RuntimeError{site, err.Error()})
+		}
+		out := fn.Call(in)
+
+		if n := len(out); n > 0 && t.Out(n-1) == errorType {
+			if err, _ := out[n-1].Interface().(error); err != nil {
+				panic(RuntimeError{site, err.Error()})
+			}
+			out = out[:n-1]
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		value, err := toLox(out[0].Interface(), "")
+		if err != nil {
+			panic(RuntimeError{site, err.Error()})
+		}
+		interpreter.lox.allocate(sizeOfAll(value), site, interpreter.env)
+		return value
+	}}, nil
+}
+
+// goArguments converts the arguments of a call to the parameters of a func of type t
+func goArguments(name string, t reflect.Type, args []interface{}) ([]reflect.Value, error) {
+	params := t.NumIn()
+	if t.IsVariadic() && len(args) < params-1 {
+		return nil, fmt.Errorf("expect at least %d arguments but got %d.", params-1, len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var param reflect.Type
+		if t.IsVariadic() && i >= params-1 {
+			param = t.In(params - 1).Elem()
+		} else {
+			param = t.In(i)
+		}
+		value, ok := goValue(arg, param)
+		if !ok {
+			return nil, fmt.Errorf("%s expects %s as argument %d, got %s", name, param, i+1, inspect(arg))
+		}
+		in[i] = value
+	}
+	return in, nil
+}
+
+// goValue converts a lox value to the go type t, ok is false when it can't
+func goValue(value interface{}, t reflect.Type) (converted reflect.Value, ok bool) {
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), true
+		}
+		return reflect.Value{}, false
+	}
+	if t == refType {
+		return reflect.ValueOf(Ref{value}), true
+	}
+	if t.Kind() == reflect.Interface {
+		v := reflect.ValueOf(ToGo(value))
+		return v, v.Type().Implements(t)
+	}
+	if object, isObject := value.(*goObject); isObject {
+		if object.value.Type().AssignableTo(t) {
+			return object.value, true
+		}
+		if object.value.Elem().Type().AssignableTo(t) {
+			return object.value.Elem(), true
+		}
+		return reflect.Value{}, false
+	}
+
+	converted = reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		b, ok := value.(bool)
+		converted.SetBool(b)
+		return converted, ok
+	case reflect.String:
+		str, ok := value.(string)
+		converted.SetString(str)
+		return converted, ok
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// the range is checked on the float, converting one out of range to an int is undefined.
+		// NaN fails the first check, infinities the second.
+		num, ok := value.(float64)
+		limit := math.Ldexp(1, t.Bits()-1)
+		if !ok || num != math.Trunc(num) || num < -limit || num >= limit {
+			return reflect.Value{}, false
+		}
+		converted.SetInt(int64(num))
+		return converted, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		num, ok := value.(float64)
+		if !ok || num != math.Trunc(num) || num < 0 || num >= math.Ldexp(1, t.Bits()) {
+			return reflect.Value{}, false
+		}
+		converted.SetUint(uint64(num))
+		return converted, true
+	case reflect.Float32, reflect.Float64:
+		// a float32 can't hold the largest numbers, they would become infinities
+		num, ok := value.(float64)
+		if !ok || converted.OverflowFloat(num) {
+			return reflect.Value{}, false
+		}
+		converted.SetFloat(num)
+		return converted, true
+	case reflect.Slice:
+		list, ok := value.(*List)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		converted = reflect.MakeSlice(t, len(list.elements), len(list.elements))
+		for i, element := range list.elements {
+			element, ok := goValue(element, t.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			converted.Index(i).Set(element)
+		}
+		return converted, true
+	case reflect.Map:
+		m, ok := value.(*Map)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		converted = reflect.MakeMapWithSize(t, len(m.order))
+		for _, key := range m.order {
+			k, ok := goValue(key, t.Key())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			v, ok := goValue(m.entries[key], t.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			converted.SetMapIndex(k, v)
+		}
+		return converted, true
+	}
+	return reflect.Value{}, false
+}
+
+// funcName is the name go gave fn, without its package
+func funcName(fn reflect.Value) string {
+	name := runtime.FuncForPC(fn.Pointer()).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// goObject shows a go struct to lox. Its exported fields are properties,
+// and its exported methods can be called, converted like the natives goFunction makes.
+// A property is the field tagged `lox:"name"` with its name,
+// or else the field or method named like it with its first letter in upper case.
+type goObject struct {
+	// value points to the struct, setting a property sets the field in go too
+	value reflect.Value
+}
+
+func (o *goObject) String() string {
+	if stringer, ok := o.value.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return "<go " + o.value.Elem().Type().String() + ">"
+}
+
+func (o *goObject) get(name Token) (interface{}, error) {
+	if field, ok := o.field(name.literal); ok {
+		// nested structs are shared too
+		if field.Kind() == reflect.Struct {
+			return &goObject{field.Addr()}, nil
+		}
+		value, err := toLox(field.Interface(), name.literal)
+		if err != nil {
+			return nil, RuntimeError{name, err.Error()}
+		}
+		return value, nil
+	}
+
+	if method := o.value.MethodByName(exportedName(name.literal)); method.IsValid() {
+		native, err := goFunction(name.literal, method)
+		if err != nil {
+			return nil, RuntimeError{name, err.Error()}
+		}
+		return native, nil
+	}
+
+	return nil, RuntimeError{
+		name,
+		"Undefined property",
+	}
+}
+
+func (o *goObject) set(name Token, value interface{}) error {
+	field, ok := o.field(name.literal)
+	if !ok {
+		return RuntimeError{
+			name,
+			fmt.Sprintf("%s has no field %s", o.value.Elem().Type(), name.literal),
+		}
+	}
+	converted, ok := goValue(value, field.Type())
+	if !ok {
+		return RuntimeError{
+			name,
+			fmt.Sprintf("Field %s expects %s, got %s", name.literal, field.Type(), inspect(value)),
+		}
+	}
+	field.Set(converted)
+	return nil
+}
+
+// field finds the exported field property name refers to
+func (o *goObject) field(name string) (reflect.Value, bool) {
+	s := o.value.Elem()
+	t := s.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if tag, ok := field.Tag.Lookup("lox"); ok {
+			if tag == name {
+				return s.Field(i), true
+			}
+			continue
+		}
+		if field.Name == exportedName(name) {
+			return s.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// exportedName is name with its first letter in upper case
+func exportedName(name string) string {
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(first)) + name[size:]
+}
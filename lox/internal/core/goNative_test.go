@@ -0,0 +1,63 @@
+package core
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGoValueNumbers(t *testing.T) {
+	tests := []struct {
+		value float64
+		to    interface{}
+		ok    bool
+	}{
+		{42, int(0), true},
+		{-1, int8(0), true},
+		{127, int8(0), true},
+		{128, int8(0), false},
+		{-128, int8(0), true},
+		{-129, int8(0), false},
+		{1.5, int(0), false},
+		{math.Inf(1), int(0), false},
+		{math.Inf(-1), int64(0), false},
+		{math.NaN(), int(0), false},
+		{1e38, int(0), false},
+		{math.Ldexp(1, 63), int64(0), false},
+		{-math.Ldexp(1, 63), int64(0), true},
+		{255, uint8(0), true},
+		{256, uint8(0), false},
+		{-1, uint(0), false},
+		{math.Ldexp(1, 64), uint64(0), false},
+		{math.Inf(1), uint(0), false},
+		{math.NaN(), uint32(0), false},
+		{math.MaxFloat32, float32(0), true},
+		{1e39, float32(0), false},
+		{math.Inf(1), float32(0), true},
+		{1e300, float64(0), true},
+	}
+	for _, test := range tests {
+		to := reflect.TypeOf(test.to)
+		converted, ok := goValue(test.value, to)
+		if ok != test.ok {
+			t.Errorf("converting %g to %s: ok is %v", test.value, to, ok)
+			continue
+		}
+		if ok && converted.Convert(reflect.TypeOf(float64(0))).Float() != test.value {
+			t.Errorf("converting %g to %s gave %v", test.value, to, converted)
+		}
+	}
+}
+
+func TestGoFuncRejectsOutOfRangeNumbers(t *testing.T) {
+	runtime, _ := NewRuntime(Options{})
+	runtime.SetGlobal("show", func(n int) int { return n })
+	for _, src := range []string{"show(1/0);", "show(100000000000000000000000000000000000000);", "show(0/0);"} {
+		if _, err := runtime.Eval(src); err == nil {
+			t.Errorf("%s ran", src)
+		}
+	}
+	if value, err := runtime.Eval("show(-12);"); err != nil || value != -12.0 {
+		t.Errorf("show(-12) is %v, %v", value, err)
+	}
+}
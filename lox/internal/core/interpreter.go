@@ -0,0 +1,829 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RuntimeError unwinds the interpreter by panicking, Lox.interpret recovers it
+type RuntimeError struct {
+	token Token
+	msg   string
+}
+
+func (e RuntimeError) Error() string {
+	return fmt.Sprintf("[GLOX] RuntimeError: Line %d, Cloumn %d, %s", e.token.line, e.token.column, e.msg)
+}
+
+// in lox, we treat everything as True except `nil` and `false`
+func toBool(val interface{}) bool {
+	if val == nil || val == false {
+		return false
+	}
+	return true
+}
+
+// Interpreter is for evaluating codes
+type Interpreter struct {
+	lox *Lox
+	/* env holds a runtime envionment that can change at runtime */
+	env *env
+	/* global holds a fixed reference to the outermost global environment */
+	global *env
+	/* locals holds where the resolver found each variable use, keyed by the offset of its name token, which is unique and cheap to hash */
+	locals map[int]local
+	/* frames is the call stack, shared by every copy of the interpreter */
+	frames *[]CallFrame
+	/* completion is how the latest statement was left, shared by every copy of the interpreter */
+	completion *completion
+	/* inTry is set while a try statement of the current function protects what runs, returns can't be tail calls then */
+	inTry bool
+}
+
+// New instantiate a new interpreter
+func NewInterpreter(lox *Lox, global *env) Interpreter {
+	interpreter := Interpreter{
+		lox,
+		global,
+		global,
+		make(map[int]local, 0),
+		&[]CallFrame{},
+		&completion{},
+		false,
+	}
+
+	interpreter.init()
+	return interpreter
+}
+
+func (v Interpreter) init() {
+	// global functions
+	v.global.define("print", Print{})
+	v.global.define("Error", ErrorConstructor)
+	v.global.define("memoryUsage", memoryUsage)
+	v.defineNatives()
+}
+
+// local is where the resolver found a variable, distance scopes up in the given slot
+type local struct {
+	distance int
+	slot     int
+}
+
+func (v Interpreter) resolve(name Token, distance int, slot int) {
+	// save
+	v.locals[name.offset] = local{distance, slot}
+}
+
+func checkNumberOperands(token Token, exprs ...interface{}) {
+	for _, expr := range exprs {
+		_, ok := expr.(float64)
+		if !ok {
+			panic(RuntimeError{
+				token,
+				"invalid operation, mismatched types",
+			})
+		}
+	}
+}
+
+func checkNumberOrStringOperands(token Token, exprs ...interface{}) {
+	for _, expr := range exprs {
+		_, ok := expr.(float64)
+		_, okString := expr.(string)
+		if !ok && !okString {
+			panic(RuntimeError{
+				token,
+				"invalid operation, mismatched types",
+			})
+		}
+	}
+}
+
+func (v Interpreter) execute(stmt Stmt) {
+	v.lox.limits.step()
+	stmt.accept(v)
+}
+
+// executeBlock runs statements until one of them completes abruptly
+func (v Interpreter) executeBlock(stmts []Stmt) {
+	for _, stmt := range stmts {
+		v.execute(stmt)
+		if v.completion.kind != normal {
+			return
+		}
+	}
+}
+
+func (v Interpreter) evaluate(expr Expr) interface{} {
+	return expr.accept(v)
+}
+
+func (v Interpreter) visitExpressionStmt(stmt ExpressionStmt) {
+	v.evaluate(stmt.expression)
+}
+
+func (v Interpreter) visitIfStmt(stmt IfStmt) {
+	if toBool(v.evaluate(stmt.condition)) {
+		v.execute(stmt.consequent)
+	} else if stmt.alternate != nil {
+		v.execute(stmt.alternate)
+	}
+}
+
+func (v Interpreter) visitWhileStmt(stmt WhileStmt) {
+	for toBool(v.evaluate(stmt.condition)) {
+		if v.executeLoopBody(stmt.body, stmt.label) {
+			return
+		}
+		// desugared for loops keep their increment here, it runs after `continue` too
+		if stmt.increment != nil {
+			v.evaluate(stmt.increment)
+		}
+	}
+}
+
+// executeLoopBody runs one iteration of a loop,
+// it takes break and continue aimed at the loop and reports whether the loop should stop
+func (v Interpreter) executeLoopBody(body Stmt, label Token) (broken bool) {
+	v.execute(body)
+
+	done := v.completion
+	switch done.kind {
+	case normal:
+		return false
+	case breaking:
+		if done.targets(label) {
+			*done = completion{}
+			return true
+		}
+	case continuing:
+		if done.targets(label) {
+			*done = completion{}
+			return false
+		}
+	}
+	// a return, or a break or continue aimed at an outer loop, leaves this loop too
+	return true
+}
+
+func (v Interpreter) visitForInStmt(stmt ForInStmt) {
+	iterator := v.iterate(stmt.keyword, v.evaluate(stmt.iterable))
+
+	for iterator.hasNext() {
+		// every iteration gets its own environment, so closures capture each value separately
+		iteration := v
+		iteration.env = newEnv(v.env)
+		v.lox.allocate(sizeOf(iteration.env), stmt.keyword, iteration.env)
+		iteration.env.define(stmt.name.literal, iterator.next())
+
+		if iteration.executeLoopBody(stmt.body, stmt.label) {
+			return
+		}
+	}
+}
+
+func (v Interpreter) visitThrowStmt(stmt ThrowStmt) {
+	panic(ThrowSignal{stmt.keyword, v.evaluate(stmt.value)})
+}
+
+func (v Interpreter) visitTryStmt(stmt TryStmt) {
+	// calls that fail inside the try never pop their frames, catch and finally put the stack back
+	depth := len(*v.frames)
+
+	if stmt.finallyBody != nil {
+		// finally runs however the try statement is left,
+		// a throw, return, break or continue inside it replaces whatever was unwinding
+		defer func() {
+			err := recover()
+			if _, stopped := err.(limitSignal); stopped {
+				panic(err)
+			}
+			unwinding := append([]CallFrame{}, *v.frames...)
+			pending := *v.completion
+			*v.frames = (*v.frames)[:depth]
+			*v.completion = completion{}
+
+			v.visitBlockStmt(*stmt.finallyBody)
+			if v.completion.kind != normal {
+				return
+			}
+			*v.completion = pending
+			if err != nil {
+				*v.frames = unwinding
+				panic(err)
+			}
+		}()
+	}
+
+	// a finally block protects the catch block too, it has to run after whatever catch returns
+	protected := v
+	protected.inTry = v.inTry || stmt.finallyBody != nil
+	if stmt.catchBody == nil {
+		protected.visitBlockStmt(stmt.body)
+		return
+	}
+	protected.executeTry(stmt, depth)
+}
+
+// executeTry runs the try block, and the catch block if a lox error unwinds out of it
+func (v Interpreter) executeTry(stmt TryStmt, depth int) {
+	defer func() {
+		if err := recover(); err != nil {
+			value, ok := v.caught(err)
+			if !ok {
+				panic(err)
+			}
+			*v.frames = (*v.frames)[:depth]
+
+			// the error variable shares its environment with the catch block, like function params do
+			environment := newEnv(v.env)
+			v.lox.allocate(sizeOf(environment), stmt.name, environment)
+			environment.define(stmt.name.literal, value)
+			v.executeBlockStmt(*stmt.catchBody, environment)
+		}
+	}()
+
+	body := v
+	body.inTry = true
+	body.visitBlockStmt(stmt.body)
+}
+
+func (v Interpreter) visitImportStmt(stmt ImportStmt) {
+	v.env.define(stmt.name.literal, v.lox.importModule(stmt.path, v.runModule))
+}
+
+// runModule compiles a module and runs its top level code with its own globals, natives included
+func (v Interpreter) runModule(name string, src string, globals *env) map[string]bool {
+	stmts := v.lox.compile(name, src)
+	if v.lox.hasError {
+		return nil
+	}
+	v.env = globals
+	v.global = globals
+	v.init()
+	v.executeBlock(stmts)
+	return exportedNames(stmts)
+}
+
+func (v Interpreter) visitExportStmt(stmt ExportStmt) {
+	// the module collects its exports from the AST, the declaration runs like any other
+	v.execute(stmt.declaration)
+}
+
+func (v Interpreter) visitBreakStmt(stmt BreakStmt) {
+	*v.completion = completion{kind: breaking, label: stmt.label.literal}
+}
+
+func (v Interpreter) visitContinueStmt(stmt ContinueStmt) {
+	*v.completion = completion{kind: continuing, label: stmt.label.literal}
+}
+
+// func (v Interpreter) visitPrintStmt(stmt PrintStmt) {
+// 	value := v.evaluate(stmt.expression)
+// 	fmt.Println(value)
+// }
+
+func (v Interpreter) visitFunStmt(stmt FunStmt) {
+	v.lox.allocate(functionSize, stmt.name, v.env)
+	// let the var declaration and function declaration use the same space
+	v.env.define(stmt.name.literal, Function{
+		stmt: stmt,
+		// function's closure env is the env where the function has been declared
+		closure: v.env,
+	})
+}
+
+func (v Interpreter) visitClassStmt(stmt ClassStmt) {
+	// Two-stage variable binding process allows references to the class inside its own methods.
+	slot := v.env.define(stmt.name.literal, nil)
+	enclosing := v.env
+
+	var super *Class
+
+	if stmt.super != nil {
+		if superClass, ok := v.evaluate(*stmt.super).(Class); ok {
+			super = &superClass
+		} else {
+			panic(RuntimeError{
+				stmt.super.name,
+				stmt.super.name.literal + " is not a class",
+			})
+		}
+	}
+
+	// NOTE: the order is important! cause wo don't need `super env` in our static methods
+	staticMethods := make(map[string]Function, 0)
+	for _, fun := range stmt.staticMethods {
+		staticMethods[fun.name.literal] = Function{
+			fun,
+			v.env,
+			false,
+			stmt.name.literal,
+		}
+	}
+
+	/*
+		Unlike dynamic `this`, `super` is static.
+		`super` should always refer to the superclass of the class containing the super expression.
+		so the super will be doomed when we are visiting class declaration
+	*/
+	// Add a new env to store "super"
+	if stmt.super != nil {
+		v.env = newEnv(enclosing)
+		v.lox.allocate(sizeOf(v.env), stmt.super.name, v.env)
+		v.env.define("super", *super)
+		// recover
+		defer func() {
+			v.env = enclosing
+		}()
+	}
+
+	methods := make(map[string]Function, 0)
+	for _, fun := range stmt.methods {
+		methods[fun.name.literal] = Function{
+			fun,
+			v.env,
+			fun.name.literal == "init",
+			stmt.name.literal,
+		}
+	}
+
+	class := Class{
+		stmt.name.literal,
+		super,
+		staticMethods,
+		methods,
+		make(map[string]interface{}, 0),
+	}
+	enclosing.redefine(stmt.name.literal, slot, class)
+}
+
+func (v Interpreter) visitReturnStmt(stmt ReturnStmt) {
+	// a call in tail position is left for the returning function to make in its place
+	if call, ok := stmt.value.(CallExpr); ok && !v.inTry {
+		callee := v.evaluate(call.callee)
+		args := make([]interface{}, len(call.arguments))
+		for i, argument := range call.arguments {
+			args[i] = v.evaluate(argument)
+		}
+		*v.completion = completion{kind: returning, tail: &tailCall{callee, call.paren, args}}
+		return
+	}
+
+	var value interface{}
+	if stmt.value != nil {
+		value = v.evaluate(stmt.value)
+	}
+	*v.completion = completion{kind: returning, value: value}
+}
+
+func (v Interpreter) executeBlockStmt(stmt BlockStmt, blockEnv *env) {
+	/*
+		This is how we fully support local scope.
+		When block statement is called, store the parent scope,
+		build a new env, so the block will executed in this new one.
+		After these, restore the previous env.
+	*/
+	parent := v.env
+	v.env = blockEnv
+	defer func() {
+		v.env = parent
+	}()
+
+	v.executeBlock(stmt.statements)
+}
+
+func (v Interpreter) visitBlockStmt(stmt BlockStmt) {
+	// closures made in the block keep its environment alive
+	environment := newEnv(v.env)
+	v.lox.allocate(sizeOf(environment), unplaced, environment)
+	v.executeBlockStmt(stmt, environment)
+}
+
+func (v Interpreter) visitVarStmt(stmt VarStmt) {
+	var value interface{}
+	if stmt.init != nil {
+		value = v.evaluate(stmt.init)
+	}
+	// NOTE: Instead of implicitly initializing variables to nil,
+	// we also can make it a runtime error to access a variable that has not been initialized or assigned to
+	v.env.define(stmt.name.literal, value)
+}
+
+func (v Interpreter) visitAssignExpr(expr AssignExpr) interface{} {
+	var value interface{}
+	if expr.right != nil {
+		value = v.evaluate(expr.right)
+	}
+
+	// if distance exist, set the value there
+	// otherwise, it's in global
+	if local, ok := v.locals[expr.left.offset]; ok {
+		v.env.assignAt(local.distance, local.slot, value)
+	} else if _, err := v.global.assign(expr.left, value); err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func (v Interpreter) visitLogicalExpr(expr LogicalExpr) interface{} {
+	// logic operator is short-circuit
+	left := v.evaluate(expr.left)
+	if expr.operator.tokentype == OR {
+		if toBool(left) {
+			return left
+		}
+	} else if expr.operator.tokentype == AND {
+		if !toBool(left) {
+			return left
+		}
+	}
+
+	return v.evaluate(expr.right)
+}
+
+func (v Interpreter) visitBinaryExpr(expr BinaryExpr) interface{} {
+	value := binary(expr.operator, expr.left.accept(v), expr.right.accept(v))
+	if str, ok := value.(string); ok {
+		v.lox.allocate(sizeOf(str), expr.operator, v.env)
+	}
+	return value
+}
+
+// binary applies a binary operator, both engines share it so they agree on every corner case
+func binary(operator Token, left interface{}, right interface{}) interface{} {
+	leftFloat, leftFloatOk := left.(float64)
+	rightFloat, rightFloatOk := right.(float64)
+	leftString, leftOk := left.(string)
+	rightString, rightOk := right.(string)
+	switch operator.tokentype {
+	// The four standard arithmetic operators (+, -, *, /) apply to numbers;
+	// + also applies to strings.
+	case SLASH:
+		checkNumberOperands(operator, left, right)
+		// in sutiation like dividing a number by zero, we preserve it as go did, which results Infinity
+		return leftFloat / rightFloat
+	case MINUS:
+		checkNumberOperands(operator, left, right)
+		return leftFloat - rightFloat
+	case STAR:
+		checkNumberOperands(operator, left, right)
+		return leftFloat * rightFloat
+	case PLUS:
+		checkNumberOrStringOperands(operator, left, right)
+		if leftFloatOk && rightFloatOk {
+			return leftFloat + rightFloat
+		}
+		if leftOk && rightOk {
+			return leftString + rightString
+		}
+		if leftOk && rightFloatOk {
+			return leftString + fmt.Sprintf("%g", rightFloat)
+		}
+		if leftFloatOk && rightOk {
+			return fmt.Sprintf("%g", leftFloat) + rightString
+		}
+	// The ordering operators <, <=, >, and >= apply to operands that are ordered.
+	// which in our case is string and number;
+	case GREATER:
+		checkNumberOrStringOperands(operator, left, right)
+		if leftFloatOk && rightFloatOk {
+			return leftFloat > rightFloat
+		}
+		if leftOk && rightOk {
+			return leftString > rightString
+		}
+	case GREATER_EQUAL:
+		checkNumberOrStringOperands(operator, left, right)
+		if leftFloatOk && rightFloatOk {
+			return leftFloat >= rightFloat
+		}
+		if leftOk && rightOk {
+			return leftString >= rightString
+		}
+	case LESS:
+		checkNumberOrStringOperands(operator, left, right)
+		if leftFloatOk && rightFloatOk {
+			return leftFloat < rightFloat
+		}
+		if leftOk && rightOk {
+			return leftString < rightString
+		}
+	case LESS_EQUAL:
+		checkNumberOrStringOperands(operator, left, right)
+		if leftFloatOk && rightFloatOk {
+			return leftFloat <= rightFloat
+		}
+		if leftOk && rightOk {
+			return leftString <= rightString
+		}
+	case BANG_EQUAL:
+		return !equal(left, right)
+	case EQUAL_EQUAL:
+		return equal(left, right)
+	}
+	return nil
+}
+
+// equal tells whether two values are the same.
+// accroding to `Go Programming Language Specification`:
+// Two interface values are equal
+// if they have identical dynamic types and equal dynamic values
+// or if both have value nil.
+// so we don't need to assert their types, except for the values go can't compare:
+// classes and instances are the same when they share their fields, functions when they share their declaration and closure
+func equal(left interface{}, right interface{}) bool {
+	switch left := left.(type) {
+	case Class:
+		right, ok := right.(Class)
+		return ok && sameFields(left.fields, right.fields)
+	case ClassInstance:
+		right, ok := right.(ClassInstance)
+		return ok && sameFields(left.fields, right.fields)
+	case Function:
+		right, ok := right.(Function)
+		return ok && left.closure == right.closure && left.stmt.name.offset == right.stmt.name.offset
+	}
+	return left == right
+}
+
+// sameFields tells whether two maps of fields are the very same map
+func sameFields(a map[string]interface{}, b map[string]interface{}) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func (v Interpreter) visitGroupingExpr(expr GroupingExpr) interface{} {
+	return expr.expression.accept(v)
+}
+
+func (v Interpreter) visitLiteralExpr(expr LiteralExpr) interface{} {
+	return expr.value
+}
+
+func (v Interpreter) visitUnaryExpr(expr UnaryExpr) interface{} {
+	return unary(expr.operator, expr.right.accept(v))
+}
+
+// unary applies a unary operator
+func unary(operator Token, right interface{}) interface{} {
+	if operator.tokentype == MINUS {
+		checkNumberOperands(operator, right)
+		return -right.(float64)
+	}
+	if operator.tokentype == BANG {
+		return !toBool(right)
+	}
+	return nil
+}
+
+func (v Interpreter) visitCallExpr(expr CallExpr) interface{} {
+	// this will get us a Callable struct
+	callee := v.evaluate(expr.callee)
+	args := make([]interface{}, 0)
+
+	for _, expression := range expr.arguments {
+		args = append(args, v.evaluate(expression))
+	}
+
+	return v.call(callee, expr.paren, args)
+}
+
+// call invokes callee as if it was called at paren, natives use it to call back into lox
+func (v Interpreter) call(callee interface{}, paren Token, args []interface{}) interface{} {
+	function := checkCallable(paren, callee, len(args))
+	v.lox.limits.call(len(*v.frames))
+
+	// frames are only popped on normal returns,
+	// a RuntimeError leaves them in place for the stack trace
+	*v.frames = append(*v.frames, CallFrame{frameName(function), paren, v.env})
+	value := function.call(v, args)
+	*v.frames = (*v.frames)[:len(*v.frames)-1]
+
+	return value
+}
+
+// checkCallable makes sure callee can be called with argc arguments
+func checkCallable(paren Token, callee interface{}, argc int) Callable {
+	function, ok := callee.(Callable)
+	if !ok {
+		panic(RuntimeError{
+			paren,
+			fmt.Sprintf("%T is not a function", callee),
+		})
+	} else if argc != function.arity() && function.arity() != -1 {
+		// match their argument numbers
+		panic(RuntimeError{
+			paren,
+			fmt.Sprintf("expect %d arguments but got %d.", function.arity(), argc),
+		})
+	}
+	return function
+}
+
+func (v Interpreter) visitIdentifierExpr(expr IdentifierExpr) interface{} {
+	// if distance exist, get the value form there
+	// otherwise, it's in global
+	if local, ok := v.locals[expr.name.offset]; ok {
+		return v.env.getAt(local.distance, local.slot)
+	}
+
+	value, err := v.global.get(expr.name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func (v Interpreter) visitThisExpr(expr ThisExpr) interface{} {
+	// if distance exist, get the value form there
+	// otherwise, it's in global
+	if local, ok := v.locals[expr.keyword.offset]; ok {
+		return v.env.getAt(local.distance, local.slot)
+	}
+
+	value, err := v.global.get(expr.keyword)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func (v Interpreter) visitSuperExpr(expr SuperExpr) interface{} {
+	local := v.locals[expr.keyword.offset]
+	superClass := v.env.getAt(local.distance, local.slot)
+	// "this" is always one level nearer than "super"'s environment, and the only thing there.
+	currentInstance, ok := v.env.getAt(local.distance-1, 0).(ClassInstance)
+	if !ok {
+		// If this happens, it must be An inner error
+		panic(RuntimeError{
+			expr.keyword,
+			"illegal This binding",
+		})
+	}
+
+	method, ok := superClass.(Class).findMethod(expr.method.literal)
+	if !ok {
+		panic(RuntimeError{
+			expr.method,
+			"Undefined property name: '" + expr.method.literal + "'",
+		})
+	}
+
+	// this super class method need to bind on current instance
+	v.lox.allocate(envSize+functionSize, expr.method, v.env)
+	return method.bind(currentInstance)
+}
+
+func (v Interpreter) visitFunExpr(expr FunExpr) interface{} {
+	v.lox.allocate(functionSize, unplaced, v.env)
+	return Function{
+		stmt: FunStmt{
+			// the name is empty, its offset is where the function is, which tells it apart from others
+			Token{offset: expr.keyword.offset},
+			expr.params,
+			expr.body,
+			"",
+		},
+		// function's closure env is the env where the function has been declared
+		closure: v.env,
+	}
+}
+
+func (v Interpreter) visitInterpolationExpr(expr InterpolationExpr) interface{} {
+	var b strings.Builder
+	for _, part := range expr.parts {
+		b.WriteString(stringify(v.evaluate(part)))
+	}
+	str := b.String()
+	v.lox.allocate(sizeOf(str), unplaced, v.env)
+	return str
+}
+
+func (v Interpreter) visitListExpr(expr ListExpr) interface{} {
+	elements := make([]interface{}, len(expr.elements))
+	for i, element := range expr.elements {
+		elements[i] = v.evaluate(element)
+	}
+	list := NewList(elements)
+	v.lox.allocate(sizeOf(list), expr.bracket, v.env)
+	return list
+}
+
+func (v Interpreter) visitMapExpr(expr MapExpr) interface{} {
+	m := NewMap()
+	for i, key := range expr.keys {
+		k := v.evaluate(key)
+		checkKey(expr.brace, k)
+		m.put(k, v.evaluate(expr.values[i]))
+	}
+	v.lox.allocate(sizeOf(m), expr.brace, v.env)
+	return m
+}
+
+func (v Interpreter) visitIndexExpr(expr IndexExpr) interface{} {
+	object := v.evaluate(expr.object)
+	value := getIndex(expr.bracket, object, v.evaluate(expr.index))
+	// indexing a string makes a string of the character
+	if _, ok := object.(string); ok {
+		v.lox.allocate(sizeOf(value), expr.bracket, v.env)
+	}
+	return value
+}
+
+// getIndex reads object[i]
+func getIndex(bracket Token, object interface{}, i interface{}) interface{} {
+	// strings are indexed by characters
+	if str, ok := object.(string); ok {
+		runes := []rune(str)
+		return string(runes[index(bracket, i, len(runes))])
+	}
+
+	if indexable, ok := object.(Indexable); ok {
+		return indexable.getIndex(bracket, i)
+	}
+
+	panic(RuntimeError{
+		bracket,
+		fmt.Sprintf("%T is not indexable", object),
+	})
+}
+
+func (v Interpreter) visitIndexSetExpr(expr IndexSetExpr) interface{} {
+	// the object, the index and the value are all evaluated before anything is checked, like on the VM
+	object := v.evaluate(expr.object)
+	i := v.evaluate(expr.index)
+	value := v.evaluate(expr.value)
+	indexable := checkIndexable(expr.bracket, object)
+	if _, ok := indexable.(*Map); ok {
+		v.lox.allocate(entrySize+interfaceSize, expr.bracket, v.env)
+	}
+	indexable.setIndex(expr.bracket, i, value)
+	return value
+}
+
+// checkIndexable makes sure object supports `object[i] = value`
+func checkIndexable(bracket Token, object interface{}) Indexable {
+	if indexable, ok := object.(Indexable); ok {
+		return indexable
+	}
+
+	panic(RuntimeError{
+		bracket,
+		fmt.Sprintf("%T does not support index assignment", object),
+	})
+}
+
+func (v Interpreter) visitConditionExpr(expr ConditionExpr) interface{} {
+	test := expr.test.accept(v)
+	if toBool(test) {
+		return expr.consequent.accept(v)
+	}
+	return expr.alternate.accept(v)
+}
+
+func (v Interpreter) visitSequenceExpr(expr SequenceExpr) interface{} {
+	// Take the last one of expressions to be the sequence result
+	return expr.exprs[len(expr.exprs)-1].accept(v)
+}
+
+func (v Interpreter) visitSetExpr(expr SetExpr) interface{} {
+	// the object and the value are both evaluated before anything is checked, like on the VM
+	object := v.evaluate(expr.object)
+	value := v.evaluate(expr.value)
+	obj := checkObject(expr.name, object)
+	v.lox.allocate(entrySize+len(expr.name.literal), expr.name, v.env)
+	if err := obj.set(expr.name, value); err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func (v Interpreter) visitGetExpr(expr GetExpr) interface{} {
+	object := v.evaluate(expr.object)
+	value := getProperty(expr.name, object)
+	v.lox.allocate(propertySize(object, expr.name, value), expr.name, v.env)
+	return value
+}
+
+// getProperty reads object.name
+func getProperty(name Token, object interface{}) interface{} {
+	value, err := checkObject(name, object).get(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// checkObject makes sure object has properties
+func checkObject(name Token, object interface{}) Object {
+	if obj, ok := object.(Object); ok {
+		return obj
+	}
+
+	panic(RuntimeError{
+		name,
+		fmt.Sprintf("%T is not a object", object),
+	})
+}
@@ -0,0 +1,51 @@
+package core
+
+import "testing"
+
+// expectPrints runs src on both engines, which must print want and not fail
+func expectPrints(t *testing.T, src string, want string) {
+	t.Helper()
+	for _, vm := range []bool{false, true} {
+		printed, err := runOn(vm, src)
+		if err != nil || printed != want {
+			t.Errorf("vm %v: printed %q and failed with %v, want %q", vm, printed, err, want)
+		}
+	}
+}
+
+func TestBreakContinue(t *testing.T) {
+	expectPrints(t, `
+		for (var i = 0; i < 5; i = i + 1) {
+			if (i == 1) continue;
+			if (i == 3) break;
+			print(i);
+		}
+		var n = 0;
+		while (true) { n = n + 1; if (n < 3) continue; break; }
+		print(n);
+	`, "0\n2\n3\n")
+
+	// a label picks the loop, continue still runs the increment of a for loop
+	expectPrints(t, `
+		outer: for (var i = 0; i < 3; i = i + 1) {
+			inner: for (var j = 0; j < 3; j = j + 1) {
+				if (j == 1) continue outer;
+				if (i == 2) break outer;
+				print("${i} ${j}");
+			}
+		}
+		print("done");
+	`, "0 0\n1 0\ndone\n")
+
+	tests := map[string]string{
+		"break;":                                   "Illegal break statement, it must be inside a loop",
+		"while (true) { fun f() { continue; } }":   "Illegal continue statement, it must be inside a loop",
+		"outer: while (true) { break inner; }":     "Undefined label 'inner'",
+		"a: while (true) {} while (true) break a;": "Undefined label 'a'",
+	}
+	for src, msg := range tests {
+		if diagnostics := compileDiagnostics(src); len(diagnostics) != 1 || diagnostics[0].msg != msg {
+			t.Errorf("%s reported %v, want %s", src, diagnostics, msg)
+		}
+	}
+}
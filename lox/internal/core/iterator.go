@@ -0,0 +1,128 @@
+package core
+
+import "fmt"
+
+// Iterator walks over the values of something for-in can loop over
+type Iterator interface {
+	hasNext() bool
+	next() interface{}
+}
+
+// Iterable is implemented by native values that know how to iterate themselves
+type Iterable interface {
+	iterator() Iterator
+}
+
+// iterate returns an iterator over value, token is where errors are reported
+func (v Interpreter) iterate(token Token, value interface{}) Iterator {
+	switch value := value.(type) {
+	case Iterable:
+		return value.iterator()
+	case string:
+		return &stringIterator{[]rune(value), 0}
+	case Object:
+		// user classes are iterable through an `iterator()` method,
+		// returning an object with `hasNext()` and `next()` methods
+		if method, err := value.get(nameToken(token, "iterator")); err == nil {
+			iterator := v.call(method, token, []interface{}{})
+			if object, ok := iterator.(Object); ok {
+				return &instanceIterator{v, token, object}
+			}
+			panic(RuntimeError{token, fmt.Sprintf("iterator() must return an object, got %s", stringify(iterator))})
+		}
+	}
+
+	panic(RuntimeError{token, fmt.Sprintf("%s is not iterable", stringify(value))})
+}
+
+type listIterator struct {
+	list  *List
+	index int
+}
+
+// the list is read as it is, so elements pushed while looping are visited too
+func (i *listIterator) hasNext() bool {
+	return i.index < len(i.list.elements)
+}
+
+func (i *listIterator) next() interface{} {
+	i.index++
+	return i.list.elements[i.index-1]
+}
+
+func (l *List) iterator() Iterator {
+	return &listIterator{l, 0}
+}
+
+// mapIterator yields the keys of a map
+type mapIterator struct {
+	m *Map
+	// keys is a copy, the map may change while looping
+	keys  []interface{}
+	index int
+}
+
+func (i *mapIterator) hasNext() bool {
+	// skip keys deleted while looping
+	for i.index < len(i.keys) {
+		if _, ok := i.m.entries[i.keys[i.index]]; ok {
+			return true
+		}
+		i.index++
+	}
+	return false
+}
+
+func (i *mapIterator) next() interface{} {
+	i.index++
+	return i.keys[i.index-1]
+}
+
+func (m *Map) iterator() Iterator {
+	keys := make([]interface{}, len(m.order))
+	copy(keys, m.order)
+	return &mapIterator{m, keys, 0}
+}
+
+// stringIterator yields the characters of a string
+type stringIterator struct {
+	runes []rune
+	index int
+}
+
+func (i *stringIterator) hasNext() bool {
+	return i.index < len(i.runes)
+}
+
+func (i *stringIterator) next() interface{} {
+	i.index++
+	return string(i.runes[i.index-1])
+}
+
+// instanceIterator drives an iterator object written in lox
+type instanceIterator struct {
+	interpreter Interpreter
+	token       Token
+	object      Object
+}
+
+func (i *instanceIterator) callMethod(name string) interface{} {
+	method, err := i.object.get(nameToken(i.token, name))
+	if err != nil {
+		panic(RuntimeError{i.token, "Iterator object has no " + name + "() method"})
+	}
+	return i.interpreter.call(method, i.token, []interface{}{})
+}
+
+func (i *instanceIterator) hasNext() bool {
+	return toBool(i.callMethod("hasNext"))
+}
+
+func (i *instanceIterator) next() interface{} {
+	return i.callMethod("next")
+}
+
+// nameToken makes an identifier token for name, located at token
+func nameToken(token Token, name string) Token {
+	return Token{tokentype: IDENTIFIER, lexeme: name, literal: name, line: token.line, column: token.column, offset: token.offset, length: token.length}
+}
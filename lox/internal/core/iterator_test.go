@@ -0,0 +1,34 @@
+package core
+
+import "testing"
+
+func TestForIn(t *testing.T) {
+	expectPrints(t, `
+		var xs = [1, 2];
+		for (var x in xs) { if (x < 3) xs.push(x + 2); print(x); }
+		var m = {"b": 1, "a": 2, "c": 3};
+		for (var k in m) { if (k == "b") m.delete("a"); print(k); }
+		for (var c in "hé!") print(c);
+		class Range {
+			init(from, to) { this.from = from; this.to = to; }
+			iterator() { return RangeIterator(this.from, this.to); }
+		}
+		class RangeIterator {
+			init(at, to) { this.at = at; this.to = to; }
+			hasNext() { return this.at < this.to; }
+			next() { this.at = this.at + 1; return this.at - 1; }
+		}
+		for (var i in Range(0, 3)) print(i);
+	`, "1\n2\n3\n4\nb\nc\nh\né\n!\n0\n1\n2\n")
+
+	for src, msg := range map[string]string{
+		"for (var x in 1) {}": "1 is not iterable",
+		"class A { iterator() { return 1; } } for (var x in A()) {}": "iterator() must return an object, got 1",
+	} {
+		for _, vm := range []bool{false, true} {
+			if _, err := runOn(vm, src); err == nil || err.(*Error).Message != msg {
+				t.Errorf("vm %v: %s failed with %v, want %s", vm, src, err, msg)
+			}
+		}
+	}
+}
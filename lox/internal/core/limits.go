@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"errors"
+)
+
+// errors of the limits that stop programs, see lox.ErrStepLimit
+var (
+	ErrStepLimit  = errors.New("lox: step limit exceeded")
+	ErrDepthLimit = errors.New("lox: call depth limit exceeded")
+)
+
+// DefaultMaxDepth is the depth limit of a Runtime made without one, see lox.DefaultMaxDepth
+const DefaultMaxDepth = 10000
+
+// contextCheckInterval is how many steps run between two checks of the context, checking it every step is not cheap
+const contextCheckInterval = 1024
+
+// limitSignal unwinds a program a limit stopped, straight to the host.
+// Unlike RuntimeError and ThrowSignal, neither catch nor finally blocks see it.
+type limitSignal struct {
+	err error
+}
+
+// limits stops programs that run too long, too deep, or past the end of their context.
+// A step is a statement on the interpreter and an instruction on the VM.
+type limits struct {
+	ctx      context.Context
+	maxSteps int
+	maxDepth int
+	steps    int
+	// next is the step the limits are checked again at, counting up to it is all most steps cost
+	next int
+}
+
+// start resets the budget for a program run until ctx is done
+func (l *limits) start(ctx context.Context) {
+	l.ctx = ctx
+	l.steps = 0
+	l.next = 0
+}
+
+// step counts a step, and stops the program once it is over its budget or its context is done
+func (l *limits) step() {
+	l.steps++
+	if l.steps >= l.next {
+		l.check()
+	}
+}
+
+func (l *limits) check() {
+	if l.maxSteps > 0 && l.steps > l.maxSteps {
+		panic(limitSignal{ErrStepLimit})
+	}
+	if l.ctx != nil {
+		select {
+		case <-l.ctx.Done():
+			panic(limitSignal{l.ctx.Err()})
+		default:
+		}
+	}
+
+	l.next = l.steps + contextCheckInterval
+	if l.maxSteps > 0 && l.maxSteps+1 < l.next {
+		l.next = l.maxSteps + 1
+	}
+}
+
+// call stops the program when a call would nest deeper than allowed, depth is how many calls are ongoing
+func (l *limits) call(depth int) {
+	if l.maxDepth > 0 && depth >= l.maxDepth {
+		panic(limitSignal{ErrDepthLimit})
+	}
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"fmt"
+	"math"
+)
+
+// List is the native value behind `[1, 2, 3]` literals,
+// it's always used as *List so lists are shared and compared by reference
+type List struct {
+	elements []interface{}
+}
+
+// NewList wraps elements in a lox list
+func NewList(elements []interface{}) *List {
+	return &List{elements}
+}
+
+func (l *List) String() string {
+	return inspect(l)
+}
+
+// index checks that value is a valid position in a sequence of length n
+func index(bracket Token, value interface{}, n int) int {
+	num, ok := value.(float64)
+	if !ok || num != math.Trunc(num) {
+		panic(RuntimeError{bracket, fmt.Sprintf("Index must be an integer, got %s", inspect(value))})
+	}
+	if num < 0 || int(num) >= n {
+		panic(RuntimeError{bracket, fmt.Sprintf("Index %d out of range [0, %d)", int(num), n)})
+	}
+	return int(num)
+}
+
+func (l *List) getIndex(bracket Token, i interface{}) interface{} {
+	return l.elements[index(bracket, i, len(l.elements))]
+}
+
+func (l *List) setIndex(bracket Token, i interface{}, value interface{}) {
+	l.elements[index(bracket, i, len(l.elements))] = value
+}
+
+func (l *List) get(name Token) (interface{}, error) {
+	if method, ok := l.method(name); ok {
+		return method, nil
+	}
+
+	return nil, RuntimeError{
+		name,
+		"Undefined property",
+	}
+}
+
+func (l *List) set(name Token, value interface{}) error {
+	return RuntimeError{
+		name,
+		"Cannot set properties on a list",
+	}
+}
+
+// method returns the list method called name, bound to l
+func (l *List) method(name Token) (*NativeFunction, bool) {
+	switch name.literal {
+	case "push":
+		return &NativeFunction{"push", 1, func(interpreter Interpreter, args []interface{}) interface{} {
+			interpreter.lox.allocate(interfaceSize, name, interpreter.env)
+			l.elements = append(l.elements, args[0])
+			return float64(len(l.elements))
+		}}, true
+	case "pop":
+		return &NativeFunction{"pop", 0, func(interpreter Interpreter, args []interface{}) interface{} {
+			if len(l.elements) == 0 {
+				panic(RuntimeError{name, "Cannot pop from an empty list"})
+			}
+			last := l.elements[len(l.elements)-1]
+			l.elements = l.elements[:len(l.elements)-1]
+			return last
+		}}, true
+	case "len":
+		return &NativeFunction{"len", 0, func(interpreter Interpreter, args []interface{}) interface{} {
+			return float64(len(l.elements))
+		}}, true
+	case "slice":
+		// slice(start, end) copies the elements in [start, end)
+		return &NativeFunction{"slice", 2, func(interpreter Interpreter, args []interface{}) interface{} {
+			// end may be the length itself
+			start := index(name, args[0], len(l.elements)+1)
+			end := index(name, args[1], len(l.elements)+1)
+			if start > end {
+				panic(RuntimeError{name, fmt.Sprintf("Invalid slice range [%d, %d)", start, end)})
+			}
+			elements := make([]interface{}, end-start)
+			copy(elements, l.elements[start:end])
+			list := NewList(elements)
+			interpreter.lox.allocate(sizeOf(list), name, interpreter.env)
+			return list
+		}}, true
+	case "map":
+		return &NativeFunction{"map", 1, func(interpreter Interpreter, args []interface{}) interface{} {
+			list := NewList(make([]interface{}, len(l.elements)))
+			interpreter.lox.allocate(sizeOf(list), name, interpreter.env)
+			for i, element := range l.elements {
+				list.elements[i] = interpreter.call(args[0], name, []interface{}{element})
+			}
+			return list
+		}}, true
+	case "filter":
+		return &NativeFunction{"filter", 1, func(interpreter Interpreter, args []interface{}) interface{} {
+			elements := make([]interface{}, 0)
+			for _, element := range l.elements {
+				if toBool(interpreter.call(args[0], name, []interface{}{element})) {
+					elements = append(elements, element)
+				}
+			}
+			list := NewList(elements)
+			interpreter.lox.allocate(sizeOf(list), name, interpreter.env)
+			return list
+		}}, true
+	case "reduce":
+		// reduce(fn, initial) folds the list from the left with fn(accumulator, element)
+		return &NativeFunction{"reduce", 2, func(interpreter Interpreter, args []interface{}) interface{} {
+			accumulator := args[1]
+			for _, element := range l.elements {
+				accumulator = interpreter.call(args[0], name, []interface{}{accumulator, element})
+			}
+			return accumulator
+		}}, true
+	}
+
+	return nil, false
+}
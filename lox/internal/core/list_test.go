@@ -0,0 +1,17 @@
+package core
+
+import "testing"
+
+func TestPrintListHoldingItself(t *testing.T) {
+	expectPrints(t, `
+		var a = [1];
+		a[0] = a;
+		print(a);
+		var b = [1, 2];
+		b.push(b);
+		print(b, [b]);
+		var c = [1];
+		print([c, c]);
+		print("${b}");
+	`, "[[...]]\n[1, 2, [...]] [[1, 2, [...]]]\n[[1], [1]]\n[1, 2, [...]]\n")
+}
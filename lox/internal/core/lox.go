@@ -0,0 +1,154 @@
+package core
+
+import (
+	"io"
+	"strings"
+)
+
+// Lox holds everything a Runtime compiles and runs programs with
+type Lox struct {
+	errorReporter *ErrorReporter
+	scanner       *Scanner
+	parser        *Parser
+	// interpreter lives as long as the Lox instance,
+	// so globals and resolved locals survive between REPL inputs
+	interpreter Interpreter
+	// vm runs programs instead of the interpreter when set, see Options.VM
+	vm *VM
+	// stdout is where print writes, stderr where diagnostics are rendered
+	stdout io.Writer
+	stderr io.Writer
+	limits limits
+	memory memory
+	// allowed holds the capabilities scripts may use, see Options.Allow
+	allowed map[string]bool
+	// stopped is the limit error that stopped the latest program, see limitSignal
+	stopped error
+	// files holds the source of what was compiled so far, in order, diagnostics quote lines from it.
+	// Tokens are located by their offset among all of them.
+	files []sourceFile
+	// end is the offset the next file compiled starts at, offsets are never reused
+	end int
+	// lines counts the lines compiled so far from each file, REPL inputs keep counting
+	lines map[string]int
+	// topLevel holds the offsets of the locals resolved outside of functions, see release
+	topLevel []int
+	filename string
+	// modules caches imported modules by resolved path
+	modules map[string]*Module
+	// importing holds the modules being loaded, innermost last
+	importing []string
+	// hasError means the source could not be compiled
+	hasError bool
+	// hadRuntimeError means the program was unwound by a RuntimeError
+	hadRuntimeError bool
+}
+
+// sourceFile is a compiled file or REPL input, its tokens are located from offset on
+type sourceFile struct {
+	name   string
+	offset int
+	// src is empty once it is released, see Lox.release
+	src string
+}
+
+// interpret runs fn, a RuntimeError or an uncaught throw unwinding out of it becomes a diagnostic
+func (l *Lox) interpret(fn func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			frames := *l.interpreter.frames
+			switch err := err.(type) {
+			case RuntimeError:
+				l.errorReporter.reportUncaught(err, traceback(frames, err.token))
+			case ThrowSignal:
+				l.errorReporter.reportUncaught(err.uncaught(frames))
+			case limitSignal:
+				l.stopped = err.err
+			default:
+				panic(err)
+			}
+			// the calls that failed are never going to return
+			*l.interpreter.frames = (*l.interpreter.frames)[:0]
+			*l.interpreter.completion = completion{}
+			if l.vm != nil {
+				l.vm.reset()
+			}
+		}
+	}()
+
+	fn()
+}
+
+// compile turns the source of file name into resolved statements, check l.hasError before using them
+func (l *Lox) compile(name string, src string) []Stmt {
+	l.hasError = false
+	l.hadRuntimeError = false
+	l.scanner.tokens = l.parser.tokens[0:0] // empty slice
+	l.parser.reset()
+
+	// tokens are positioned relative to everything compiled before,
+	// so tokens of different REPL inputs never look the same to the resolver's locals table
+	l.scanner.lineOffset = l.lines[name]
+	l.scanner.offset = l.end
+	l.addSource(name, src)
+
+	l.scanner.source = src
+	l.scanner.scanTokens()
+	l.parser.tokens = l.scanner.tokens
+	// fmt.Println("tokens: ", l.parser.tokens)
+	stmts := l.parser.parse()
+	if l.hasError {
+		return nil
+	}
+	// check if our AST works as we expect
+	// AstPrinter{}.print(expr, os.Stdout)
+
+	// Resolving variables
+	resolver := NewResolver(l, &l.interpreter)
+	resolver.resolveBody(stmts)
+	if l.hasError {
+		return nil
+	}
+
+	return optimize(stmts)
+}
+
+// addSource adds the source of file name to l.files, for diagnostics to quote
+func (l *Lox) addSource(name string, src string) {
+	l.files = append(l.files, sourceFile{name, l.end, src})
+	l.lines[name] += strings.Count(src, "\n") + 1
+	l.end += len(src) + 1
+}
+
+// fileAt finds the file the token at offset comes from
+func (l *Lox) fileAt(offset int) sourceFile {
+	found := sourceFile{name: l.filename}
+	for _, file := range l.files {
+		if file.offset > offset {
+			break
+		}
+		found = file
+	}
+	return found
+}
+
+// release forgets what only the program just run needed.
+// Its top level code never runs again, so the locals resolved in it go,
+// functions keep theirs since they can be called later.
+// The source of an Eval goes too, diagnostics about it were rendered already,
+// later ones locate it without quoting it. eval is the index of its file in l.files, -1 for other programs.
+func (l *Lox) release(eval int) {
+	for _, offset := range l.topLevel {
+		delete(l.interpreter.locals, offset)
+	}
+	l.topLevel = l.topLevel[:0]
+
+	if eval < 0 || eval >= len(l.files) {
+		return
+	}
+	l.files[eval].src = ""
+	// released Evals in a row share a file, an offset after the previous one is in it anyway
+	if eval > 0 && l.files[eval-1].name == evalFilename && l.files[eval-1].src == "" {
+		l.files = append(l.files[:eval], l.files[eval+1:]...)
+	}
+}
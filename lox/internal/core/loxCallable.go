@@ -0,0 +1,173 @@
+package core
+
+import "fmt"
+
+// Callable interface, function and methods should both implement
+type Callable interface {
+	call(interpreter Interpreter, args []interface{}) interface{}
+	arity() int
+}
+
+// completionKind tells how the statements being run were left
+type completionKind int
+
+const (
+	// normal completions run the next statement
+	normal completionKind = iota
+	returning
+	breaking
+	continuing
+)
+
+// completion is how the latest statement was left. Once it is abrupt,
+// blocks skip the rest of their statements until a loop or a function call takes it.
+// Unlike panics, leaving this way costs nothing more than a check per statement.
+type completion struct {
+	kind completionKind
+	// label is the loop a break or continue aims at, empty for the innermost one
+	label string
+	// value is what a return returns
+	value interface{}
+	// tail is the call a return ends with, the function returning makes it in its own place
+	tail *tailCall
+}
+
+func (c *completion) targets(label Token) bool {
+	return c.label == "" || c.label == label.literal
+}
+
+// tailCall is a call in tail position, evaluated but not made yet
+type tailCall struct {
+	callee interface{}
+	paren  Token
+	args   []interface{}
+}
+
+type Function struct {
+	stmt FunStmt
+	// function declare environment, which is known as `closure`
+	closure *env
+	isInit  bool
+	// className is the class a method belongs to, empty for plain functions
+	className string
+}
+
+// CallFrame records an ongoing call for stack traces
+type CallFrame struct {
+	// name is what the traceback shows for the callee
+	name string
+	// callSite is the closing paren of the call expression
+	callSite Token
+	// env is where the caller was at, its variables are still in use, nil on the VM
+	env *env
+}
+
+// callSite is where the innermost ongoing call was made, for what the callee reports or allocates.
+// It is unplaced when there is no call going on, like for a native the host calls directly.
+func callSite(interpreter Interpreter) Token {
+	frames := *interpreter.frames
+	if len(frames) == 0 {
+		return unplaced
+	}
+	return frames[len(frames)-1].callSite
+}
+
+// frameName names a callee in stack traces
+func frameName(callee Callable) string {
+	switch callee := callee.(type) {
+	case Function:
+		return callee.name()
+	case Class:
+		return callee.name
+	case *Closure:
+		return callee.function.frameName()
+	case *BoundMethod:
+		return callee.method.function.frameName()
+	case *VMClass:
+		return callee.name
+	}
+	return fmt.Sprint(callee)
+}
+
+// TODO: Add function string representation
+func (f Function) String() string {
+	return "<fn " + f.stmt.name.literal + ">"
+}
+
+// name is how the function shows up in stack traces
+func (f Function) name() string {
+	name := f.stmt.name.literal
+	if name == "" {
+		name = "<anonymous>"
+	}
+	if f.className != "" {
+		name = f.className + "." + name
+	}
+	return name
+}
+
+func (f Function) call(interpreter Interpreter, args []interface{}) interface{} {
+	// try statements of the caller don't protect the calls this one returns with
+	interpreter.inTry = false
+
+	// a call in tail position replaces this one, looping instead of nesting keeps the go stack flat
+	for {
+		environment := newEnv(f.closure)
+		interpreter.lox.allocate(envSize+interfaceSize*len(f.stmt.params), callSite(interpreter), environment)
+
+		// build a local variable for each one param
+		for index, param := range f.stmt.params {
+			environment.define(param.literal, args[index])
+		}
+
+		// globals are the ones of the module the function was declared in
+		interpreter.global = f.closure.root()
+
+		// execute this function body in this environment
+		interpreter.executeBlockStmt(f.stmt.body, environment)
+		done := *interpreter.completion
+		*interpreter.completion = completion{}
+
+		// If the function is an initializer,
+		// We ignore the nil return value or override the actual return value,
+		// and forcibly return this.
+		if f.isInit {
+			return f.closure.slots[0]
+		}
+		if done.tail == nil {
+			return done.value
+		}
+
+		tail := done.tail
+		callee, ok := tail.callee.(Function)
+		if !ok {
+			// natives and classes don't recurse through here
+			return interpreter.call(tail.callee, tail.paren, tail.args)
+		}
+		checkCallable(tail.paren, callee, len(tail.args))
+		// the frame of the caller goes on with the callee, tracebacks show where the chain of tail calls began
+		if frames := *interpreter.frames; len(frames) > 0 {
+			frames[len(frames)-1].name = callee.name()
+		}
+		f, args = callee, tail.args
+	}
+}
+
+func (f Function) bind(instance ClassInstance) Function {
+	// Add a new env to store `this` in interpreter to sync the resolver
+	environment := newEnv(f.closure)
+
+	// this is dynamic, it refers to different instances
+	environment.define("this", instance)
+
+	return Function{
+		stmt:      f.stmt,
+		closure:   environment,
+		isInit:    f.isInit,
+		className: f.className,
+	}
+}
+
+func (f Function) arity() int {
+	return len(f.stmt.params)
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"fmt"
+	"math"
+)
+
+// Map is the native value behind `{ "key": value }` literals,
+// it remembers the order keys were inserted in and is always used as *Map
+type Map struct {
+	entries map[interface{}]interface{}
+	// order holds the keys in insertion order
+	order []interface{}
+}
+
+// NewMap creates an empty lox map
+func NewMap() *Map {
+	return &Map{
+		entries: make(map[interface{}]interface{}, 0),
+		order:   make([]interface{}, 0),
+	}
+}
+
+// checkKey makes sure key can be used in a map, only strings, numbers, booleans and nil can,
+// except NaN, whose entries could never be read back
+func checkKey(token Token, key interface{}) {
+	switch key := key.(type) {
+	case float64:
+		if !math.IsNaN(key) {
+			return
+		}
+		panic(RuntimeError{token, "Invalid map key NaN, it is never equal to itself"})
+	case nil, bool, string:
+		return
+	}
+	panic(RuntimeError{token, fmt.Sprintf("Invalid map key %s, keys must be strings, numbers, booleans or nil", stringify(key))})
+}
+
+func (m *Map) String() string {
+	return inspect(m)
+}
+
+func (m *Map) put(key interface{}, value interface{}) {
+	if _, ok := m.entries[key]; !ok {
+		m.order = append(m.order, key)
+	}
+	m.entries[key] = value
+}
+
+func (m *Map) remove(key interface{}) bool {
+	if _, ok := m.entries[key]; !ok {
+		return false
+	}
+	delete(m.entries, key)
+	for i, k := range m.order {
+		if k == key {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// getIndex returns nil for missing keys, use has to tell them from nil values
+func (m *Map) getIndex(bracket Token, key interface{}) interface{} {
+	checkKey(bracket, key)
+	return m.entries[key]
+}
+
+func (m *Map) setIndex(bracket Token, key interface{}, value interface{}) {
+	checkKey(bracket, key)
+	m.put(key, value)
+}
+
+// get looks up methods first, then the entry keyed by the property name
+func (m *Map) get(name Token) (interface{}, error) {
+	if method, ok := m.method(name); ok {
+		return method, nil
+	}
+	return m.entries[name.literal], nil
+}
+
+func (m *Map) set(name Token, value interface{}) error {
+	m.put(name.literal, value)
+	return nil
+}
+
+// method returns the map method called name, bound to m
+func (m *Map) method(name Token) (*NativeFunction, bool) {
+	switch name.literal {
+	case "keys":
+		return &NativeFunction{"keys", 0, func(interpreter Interpreter, args []interface{}) interface{} {
+			keys := make([]interface{}, len(m.order))
+			copy(keys, m.order)
+			list := NewList(keys)
+			interpreter.lox.allocate(sizeOf(list), name, interpreter.env)
+			return list
+		}}, true
+	case "values":
+		return &NativeFunction{"values", 0, func(interpreter Interpreter, args []interface{}) interface{} {
+			values := make([]interface{}, len(m.order))
+			for i, key := range m.order {
+				values[i] = m.entries[key]
+			}
+			list := NewList(values)
+			interpreter.lox.allocate(sizeOf(list), name, interpreter.env)
+			return list
+		}}, true
+	case "has":
+		return &NativeFunction{"has", 1, func(interpreter Interpreter, args []interface{}) interface{} {
+			checkKey(name, args[0])
+			_, ok := m.entries[args[0]]
+			return ok
+		}}, true
+	case "delete":
+		// delete(key) reports whether there was something to delete
+		return &NativeFunction{"delete", 1, func(interpreter Interpreter, args []interface{}) interface{} {
+			checkKey(name, args[0])
+			return m.remove(args[0])
+		}}, true
+	case "len":
+		return &NativeFunction{"len", 0, func(interpreter Interpreter, args []interface{}) interface{} {
+			return float64(len(m.order))
+		}}, true
+	case "forEach":
+		// forEach(fn) calls fn(key, value) for every entry in insertion order
+		return &NativeFunction{"forEach", 1, func(interpreter Interpreter, args []interface{}) interface{} {
+			// iterate over a copy, fn may change the map
+			keys := make([]interface{}, len(m.order))
+			copy(keys, m.order)
+			for _, key := range keys {
+				if value, ok := m.entries[key]; ok {
+					interpreter.call(args[0], name, []interface{}{key, value})
+				}
+			}
+			return nil
+		}}, true
+	}
+
+	return nil, false
+}
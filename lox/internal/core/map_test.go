@@ -0,0 +1,17 @@
+package core
+
+import "testing"
+
+func TestPrintMapHoldingItself(t *testing.T) {
+	expectPrints(t, `
+		var m = {};
+		m["self"] = m;
+		print(m);
+		var n = {"a": 1};
+		var xs = [n];
+		n["xs"] = xs;
+		print(n, xs);
+		var shared = {"k": 1};
+		print({"x": shared, "y": shared});
+	`, "{\"self\": {...}}\n{\"a\": 1, \"xs\": [{...}]} [{\"a\": 1, \"xs\": [...]}]\n{\"x\": {\"k\": 1}, \"y\": {\"k\": 1}}\n")
+}
@@ -0,0 +1,295 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// rough sizes of what go allocates for lox values, in bytes.
+// They only have to be in the right ballpark, memory is accounted for to stop runaway programs, not to profile them.
+const (
+	wordSize      = 8
+	interfaceSize = 16
+	stringSize    = 16
+	// entrySize is a map entry, with its share of the buckets
+	entrySize    = 48
+	envSize      = 48
+	listSize     = 48
+	mapSize      = 96
+	functionSize = 64
+	classSize    = 96
+	instanceSize = 64
+	upvalueSize  = 48
+	objectSize   = 32
+	errorSize    = 96
+	// sharedStringSize is the length from which strings are told apart by their bytes,
+	// a long string many variables hold is only counted once
+	sharedStringSize = 64
+)
+
+// unplaced locates allocations made by expressions with no token of their own
+var unplaced = Token{offset: -1}
+
+// memory accounts for what lox values hold, see Options.MaxMemory.
+// Allocations only ever add up, so once they add up past next,
+// what the program can still reach is measured, and counting goes on from there.
+// next is left a quarter of the limit above what was measured, so a program holding close to its limit
+// is not measured again on every allocation, and it can go over the limit by as much before it is stopped.
+// Values only held by go locals in the middle of evaluating an expression, like the operands of a binary
+// or the arguments of a call being made, are not reachable from the roots measure starts from and are not counted.
+type memory struct {
+	max       int
+	allocated int
+	next      int
+	// measured counts the measures made on allocating
+	measured int
+}
+
+// allocate accounts for size bytes a program allocates at token, current is the innermost environment of the interpreter.
+// Going over the limit raises a runtime error, scripts can catch it.
+func (l *Lox) allocate(size int, token Token, current *env) {
+	l.memory.allocated += size
+	if l.memory.max > 0 && l.memory.allocated > l.memory.next {
+		l.collect(size, token, current)
+	}
+}
+
+func (l *Lox) collect(size int, token Token, current *env) {
+	live := l.measure(current)
+	l.memory.measured++
+	l.memory.allocated = live + size
+	l.memory.next = live + size + l.memory.max/4
+	if l.memory.allocated > l.memory.max {
+		// the allocation fails, what it allocated is garbage
+		l.memory.allocated = live
+		panic(RuntimeError{
+			token,
+			fmt.Sprintf("Out of memory: the program holds %d bytes and needs %d more, over its limit of %d", live, size, l.memory.max),
+		})
+	}
+}
+
+// measure adds up the size of every value the program can reach,
+// from the globals of the modules, the environments of the ongoing calls, and the stack of the VM
+func (l *Lox) measure(current *env) int {
+	m := &measurer{seen: make(map[interface{}]bool, 0)}
+	m.add(l.interpreter.global)
+	for _, module := range l.modules {
+		m.add(module)
+	}
+	m.add(current)
+	for _, frame := range *l.interpreter.frames {
+		m.add(frame.env)
+	}
+	if l.vm != nil {
+		for _, value := range l.vm.stack {
+			m.add(value)
+		}
+		for _, frame := range l.vm.frames {
+			m.add(frame.closure)
+		}
+	}
+	m.run()
+	return m.size
+}
+
+// memoryUsage is the `memoryUsage()` native, it returns how many bytes the program holds
+var memoryUsage = &NativeFunction{"memoryUsage", 0, func(interpreter Interpreter, args []interface{}) interface{} {
+	return float64(interpreter.lox.measure(interpreter.env))
+}}
+
+// measurer walks a graph of lox values once each, without recursing, so deep lists don't overflow the go stack
+type measurer struct {
+	seen    map[interface{}]bool
+	pending []interface{}
+	size    int
+}
+
+// sharedString tells long strings apart by the bytes they point to
+type sharedString struct {
+	data uintptr
+	len  int
+}
+
+// sharedFields tells class values apart by their fields, they are copied around but share them
+type sharedFields uintptr
+
+// add counts value in, it is walked later unless it was seen already
+func (m *measurer) add(value interface{}) {
+	var key interface{}
+	switch value := value.(type) {
+	case nil, bool, float64:
+		return
+	case string:
+		if len(value) < sharedStringSize {
+			m.size += stringSize + len(value)
+			return
+		}
+		key = sharedString{(*reflect.StringHeader)(unsafe.Pointer(&value)).Data, len(value)}
+		if !m.seen[key] {
+			m.seen[key] = true
+			m.size += stringSize + len(value)
+		}
+		return
+	case Function:
+		// functions are values, the environment they close over is what they share
+		m.size += functionSize
+		m.add(value.closure)
+		return
+	case Class:
+		key = sharedFields(reflect.ValueOf(value.fields).Pointer())
+	case ClassInstance:
+		key = sharedFields(reflect.ValueOf(value.fields).Pointer())
+	case *env, *List, *Map, *Closure, *Upvalue, *VMClass, *VMInstance, *BoundMethod, *Module, *ErrorObject, *goObject:
+		if reflect.ValueOf(value).IsNil() {
+			return
+		}
+		key = value
+	default:
+		// natives and iterators, what they hold is reachable some other way
+		m.size += objectSize
+		return
+	}
+	if m.seen[key] {
+		return
+	}
+	m.seen[key] = true
+	m.pending = append(m.pending, value)
+}
+
+// run walks what add left pending, adding what each value holds
+func (m *measurer) run() {
+	for len(m.pending) > 0 {
+		value := m.pending[len(m.pending)-1]
+		m.pending = m.pending[:len(m.pending)-1]
+		m.visit(value)
+	}
+}
+
+func (m *measurer) visit(value interface{}) {
+	switch value := value.(type) {
+	case *env:
+		m.size += envSize + interfaceSize*cap(value.slots)
+		m.addFields(value.values)
+		for _, slot := range value.slots {
+			m.add(slot)
+		}
+		m.add(value.parent)
+	case *List:
+		m.size += listSize + interfaceSize*cap(value.elements)
+		for _, element := range value.elements {
+			m.add(element)
+		}
+	case *Map:
+		m.size += mapSize + (entrySize+interfaceSize)*len(value.order)
+		for _, key := range value.order {
+			m.add(key)
+			m.add(value.entries[key])
+		}
+	case Class:
+		m.size += classSize
+		m.addFields(value.fields)
+		for _, method := range value.methods {
+			m.add(method)
+		}
+		for _, method := range value.staticMethods {
+			m.add(method)
+		}
+		if value.super != nil {
+			m.add(*value.super)
+		}
+	case ClassInstance:
+		m.size += instanceSize
+		m.addFields(value.fields)
+		m.add(value.class)
+	case *Closure:
+		m.size += functionSize + wordSize*len(value.upvalues)
+		for _, upvalue := range value.upvalues {
+			m.add(upvalue)
+		}
+		m.add(value.globals)
+	case *Upvalue:
+		// open upvalues point into the stack, which is measured already
+		m.size += upvalueSize
+		if !value.open {
+			m.add(value.value)
+		}
+	case *VMClass:
+		m.size += classSize
+		m.addFields(value.fields)
+		for _, method := range value.methods {
+			m.add(method)
+		}
+		for _, method := range value.staticMethods {
+			m.add(method)
+		}
+		m.add(value.super)
+	case *VMInstance:
+		m.size += instanceSize
+		m.addFields(value.fields)
+		m.add(value.class)
+	case *BoundMethod:
+		m.size += objectSize
+		m.add(value.receiver)
+		m.add(value.method)
+	case *Module:
+		m.size += objectSize
+		m.add(value.globals)
+	case *ErrorObject:
+		m.size += errorSize + len(value.message) + objectSize*len(value.trace)
+	case *goObject:
+		m.size += objectSize + int(value.value.Elem().Type().Size())
+	}
+}
+
+// addFields adds the entries of a map of variables or fields
+func (m *measurer) addFields(fields map[string]interface{}) {
+	for name, value := range fields {
+		m.size += entrySize + len(name)
+		m.add(value)
+	}
+}
+
+// sizeOf is the size of the value itself, without what it holds, for the values operators and natives make
+func sizeOf(value interface{}) int {
+	switch value := value.(type) {
+	case string:
+		return stringSize + len(value)
+	case *List:
+		return listSize + interfaceSize*cap(value.elements)
+	case *Map:
+		return mapSize + (entrySize+interfaceSize)*len(value.order)
+	case *env:
+		return envSize + interfaceSize*cap(value.slots)
+	case *ErrorObject:
+		return errorSize + len(value.message) + objectSize*len(value.trace)
+	}
+	return 0
+}
+
+// sizeOfAll is the size of value along with everything it holds, for values made whole at once, like what go funcs return
+func sizeOfAll(value interface{}) int {
+	m := &measurer{seen: make(map[interface{}]bool, 0)}
+	m.add(value)
+	m.run()
+	return m.size
+}
+
+// propertySize is what reading the property name of object allocates.
+// Go structs convert their fields and errors build their stack every time, methods are bound to the instance they are read from.
+func propertySize(object interface{}, name Token, value interface{}) int {
+	switch object := object.(type) {
+	case *goObject, *ErrorObject:
+		return sizeOfAll(value)
+	case ClassInstance:
+		if _, isField := object.fields[name.literal]; !isField {
+			return envSize + functionSize
+		}
+	case *VMInstance:
+		if _, isField := object.fields[name.literal]; !isField {
+			return objectSize
+		}
+	}
+	return 0
+}
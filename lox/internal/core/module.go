@@ -0,0 +1,139 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Module is the value an import binds, it only shows the names its file exports
+type Module struct {
+	// path is the resolved path the module is cached by
+	path    string
+	globals *env
+	exports map[string]bool
+	// loading is true while the module's top level code runs, importing it then is a cycle
+	loading bool
+}
+
+func (m *Module) String() string {
+	return "<module " + m.path + ">"
+}
+
+// get reads the exported global, so modules see later assignments to it
+func (m *Module) get(name Token) (interface{}, error) {
+	if m.exports[name.literal] {
+		return m.globals.values[name.literal], nil
+	}
+	return nil, RuntimeError{
+		name,
+		fmt.Sprintf("Module '%s' does not export '%s'", m.path, name.literal),
+	}
+}
+
+func (m *Module) set(name Token, value interface{}) error {
+	return RuntimeError{
+		name,
+		"Cannot assign to a module export",
+	}
+}
+
+// exportedNames collects what the top level export declarations of a module define
+func exportedNames(stmts []Stmt) map[string]bool {
+	exports := make(map[string]bool, 0)
+	for _, stmt := range stmts {
+		export, ok := stmt.(ExportStmt)
+		if !ok {
+			continue
+		}
+		switch declaration := export.declaration.(type) {
+		case VarStmt:
+			exports[declaration.name.literal] = true
+		case FunStmt:
+			exports[declaration.name.literal] = true
+		case ClassStmt:
+			exports[declaration.name.literal] = true
+		}
+	}
+	return exports
+}
+
+// resolveImport finds the file an import refers to.
+// Relative paths are looked up next to the importing file first, then in every GLOX_PATH root.
+func (l *Lox) resolveImport(path Token) (string, error) {
+	name := path.lexeme.(string)
+
+	candidates := []string{name}
+	if !filepath.IsAbs(name) {
+		candidates = candidates[:0]
+		if importer := l.fileAt(path.offset).name; importer != replFilename && importer != evalFilename {
+			candidates = append(candidates, filepath.Join(filepath.Dir(importer), name))
+		} else {
+			candidates = append(candidates, name)
+		}
+		for _, root := range filepath.SplitList(os.Getenv("GLOX_PATH")) {
+			if root != "" {
+				candidates = append(candidates, filepath.Join(root, name))
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return filepath.Abs(candidate)
+		}
+	}
+	return "", RuntimeError{path, "Cannot find module '" + name + "'"}
+}
+
+// importModule runs the module at path once with the given engine, later imports get the cached module.
+// run compiles the module's source, runs it with the module's globals and returns the names it exports.
+func (l *Lox) importModule(path Token, run func(name string, src string, globals *env) map[string]bool) *Module {
+	resolved, err := l.resolveImport(path)
+	if err != nil {
+		panic(err)
+	}
+
+	if module, ok := l.modules[resolved]; ok {
+		if module.loading {
+			cycle := append(append([]string{}, l.importing...), resolved)
+			for i := range cycle {
+				cycle[i] = filepath.Base(cycle[i])
+			}
+			panic(RuntimeError{path, "Import cycle: " + strings.Join(cycle, " -> ")})
+		}
+		return module
+	}
+
+	content, err := ioutil.ReadFile(resolved)
+	if err != nil {
+		panic(RuntimeError{path, "Cannot read module '" + resolved + "'"})
+	}
+
+	module := &Module{
+		path:    resolved,
+		globals: newGlobals(),
+		loading: true,
+	}
+	l.modules[resolved] = module
+	l.importing = append(l.importing, resolved)
+	defer func() {
+		l.importing = l.importing[:len(l.importing)-1]
+		module.loading = false
+		// a module that failed halfway is not worth caching, importing it again retries
+		if err := recover(); err != nil {
+			delete(l.modules, resolved)
+			panic(err)
+		}
+	}()
+
+	exports := run(resolved, string(content), module.globals)
+	if l.hasError {
+		panic(RuntimeError{path, "Module '" + resolved + "' failed to compile"})
+	}
+	module.exports = exports
+
+	return module
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles writes files, named by their path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runFileOn runs the script at path on the VM or on the interpreter, and returns what it printed and how it failed
+func runFileOn(vm bool, path string) (string, error) {
+	var out bytes.Buffer
+	runtime, _ := NewRuntime(Options{VM: vm, Stdout: &out})
+	err := runtime.RunFile(path)
+	return out.String(), err
+}
+
+func TestModules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"app/main.lox": `
+			import "lib/shapes.lox" as shapes;
+			import "greet.lox" as greet;
+			import "lib/shapes.lox" as again;
+			print(shapes.square(3), greet.hello("lox"), again == shapes);
+			try { shapes.hidden; } catch (e) { print(e.message); }
+		`,
+		"app/lib/shapes.lox": `
+			print("loading shapes");
+			var hidden = 1;
+			export fun square(x) { return x * x * hidden; }
+		`,
+		// only found through GLOX_PATH
+		"path/greet.lox": `export fun hello(name) { return "hello " + name; }`,
+		"app/a.lox":      `import "b.lox" as b;`,
+		"app/b.lox":      `import "a.lox" as a;`,
+	})
+	defer os.Setenv("GLOX_PATH", os.Getenv("GLOX_PATH"))
+	os.Setenv("GLOX_PATH", filepath.Join(dir, "missing")+string(filepath.ListSeparator)+filepath.Join(dir, "path"))
+
+	for _, vm := range []bool{false, true} {
+		printed, err := runFileOn(vm, filepath.Join(dir, "app/main.lox"))
+		shapes := filepath.Join(dir, "app", "lib", "shapes.lox")
+		want := "loading shapes\n9 hello lox true\nModule '" + shapes + "' does not export 'hidden'\n"
+		if err != nil || printed != want {
+			t.Errorf("vm %v: printed %q and failed with %v, want %q", vm, printed, err, want)
+		}
+
+		_, err = runFileOn(vm, filepath.Join(dir, "app/a.lox"))
+		if lerr, ok := err.(*Error); !ok || lerr.Message != "Import cycle: a.lox -> b.lox -> a.lox" {
+			t.Errorf("vm %v: the import cycle failed with %v", vm, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stringify formats a lox value the way print shows it
+func stringify(value interface{}) string {
+	return fmt.Sprint(value)
+}
+
+// inspect formats a value inside a container, strings are quoted so `["1"]` and `[1]` look different
+func inspect(value interface{}) string {
+	return format(value, make(map[interface{}]bool, 0))
+}
+
+// format formats value the way inspect does, printing holds the containers being formatted around it,
+// a container coming up again inside itself is shown as [...] or {...} instead of being formatted forever
+func format(value interface{}, printing map[interface{}]bool) string {
+	switch value := value.(type) {
+	case string:
+		return strconv.Quote(value)
+	case *List:
+		if printing[value] {
+			return "[...]"
+		}
+		printing[value] = true
+		defer delete(printing, value)
+		strs := make([]string, len(value.elements))
+		for i, element := range value.elements {
+			strs[i] = format(element, printing)
+		}
+		return "[" + strings.Join(strs, ", ") + "]"
+	case *Map:
+		if printing[value] {
+			return "{...}"
+		}
+		printing[value] = true
+		defer delete(printing, value)
+		strs := make([]string, len(value.order))
+		for i, key := range value.order {
+			strs[i] = format(key, printing) + ": " + format(value.entries[key], printing)
+		}
+		return "{" + strings.Join(strs, ", ") + "}"
+	}
+	return stringify(value)
+}
+
+// NativeFunction is a callable implemented in go, like the methods of native objects
+type NativeFunction struct {
+	name string
+	// arityN is the number of arguments, -1 accepts any number
+	arityN int
+	fn     func(interpreter Interpreter, args []interface{}) interface{}
+}
+
+func (n *NativeFunction) call(interpreter Interpreter, args []interface{}) interface{} {
+	return n.fn(interpreter, args)
+}
+
+func (n *NativeFunction) arity() int {
+	return n.arityN
+}
+
+func (n *NativeFunction) String() string {
+	return "<native fn " + n.name + ">"
+}
+
+// Clock shows current time
+type Clock struct{}
+
+func (c Clock) call(interpreter Interpreter, args []interface{}) interface{} {
+	return float64(time.Now().Unix())
+}
+
+func (c Clock) arity() int {
+	return 0
+}
+
+func (c Clock) String() string {
+	return "<native fn>"
+}
+
+// Print shows stuff
+type Print struct{}
+
+func (p Print) call(interpreter Interpreter, args []interface{}) interface{} {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = stringify(arg)
+	}
+	fmt.Fprintln(interpreter.lox.stdout, strings.Join(strs, " "))
+	return nil
+}
+
+func (p Print) arity() int {
+	return -1
+}
+
+func (p Print) String() string {
+	return "<native fn>"
+}
@@ -0,0 +1,331 @@
+package core
+
+/*
+Optimizer rewrites the resolved AST before it runs, both engines run what it returns.
+It folds operators over literals into the literal they evaluate to,
+and drops the branches of if and while statements a constant condition never takes.
+
+Folding calls the very functions the interpreter evaluates with, so it can't disagree with them.
+An operation that raises a runtime error is left alone, it raises it when it runs.
+Every node keeps its tokens, so the locals the resolver found still apply.
+*/
+type Optimizer struct {
+	// stmt is what the latest statement visited was rewritten into, nil when it was dropped
+	stmt Stmt
+}
+
+func optimize(stmts []Stmt) []Stmt {
+	return (&Optimizer{}).statements(stmts)
+}
+
+func (o *Optimizer) statements(stmts []Stmt) []Stmt {
+	optimized := make([]Stmt, 0, len(stmts))
+	for _, stmt := range stmts {
+		if stmt = o.statement(stmt); stmt != nil {
+			optimized = append(optimized, stmt)
+		}
+	}
+	return optimized
+}
+
+func (o *Optimizer) statement(stmt Stmt) Stmt {
+	o.stmt = nil
+	stmt.accept(o)
+	return o.stmt
+}
+
+// branch is statement for the body of an if or a loop, which can't be dropped but can be empty
+func (o *Optimizer) branch(stmt Stmt) Stmt {
+	if stmt = o.statement(stmt); stmt != nil {
+		return stmt
+	}
+	return BlockStmt{[]Stmt{}}
+}
+
+func (o *Optimizer) block(block BlockStmt) BlockStmt {
+	return BlockStmt{o.statements(block.statements)}
+}
+
+func (o *Optimizer) expression(expr Expr) Expr {
+	if expr == nil {
+		return nil
+	}
+	return expr.accept(o).(Expr)
+}
+
+func (o *Optimizer) expressions(exprs []Expr) []Expr {
+	optimized := make([]Expr, len(exprs))
+	for i, expr := range exprs {
+		optimized[i] = o.expression(expr)
+	}
+	return optimized
+}
+
+func (o *Optimizer) function(stmt FunStmt) FunStmt {
+	stmt.body = o.block(stmt.body)
+	return stmt
+}
+
+// fold evaluates an operation over literals, ok is false when it raised a runtime error
+func fold(operation func() interface{}) (value interface{}, ok bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			if _, isRuntimeError := err.(RuntimeError); !isRuntimeError {
+				panic(err)
+			}
+			ok = false
+		}
+	}()
+	return operation(), true
+}
+
+/* statements */
+
+func (o *Optimizer) visitExpressionStmt(stmt ExpressionStmt) {
+	o.stmt = ExpressionStmt{o.expression(stmt.expression)}
+}
+
+func (o *Optimizer) visitBlockStmt(stmt BlockStmt) {
+	o.stmt = o.block(stmt)
+}
+
+func (o *Optimizer) visitVarStmt(stmt VarStmt) {
+	stmt.init = o.expression(stmt.init)
+	o.stmt = stmt
+}
+
+func (o *Optimizer) visitClassStmt(stmt ClassStmt) {
+	methods := make([]FunStmt, len(stmt.methods))
+	for i, method := range stmt.methods {
+		methods[i] = o.function(method)
+	}
+	staticMethods := make([]FunStmt, len(stmt.staticMethods))
+	for i, method := range stmt.staticMethods {
+		staticMethods[i] = o.function(method)
+	}
+	stmt.methods = methods
+	stmt.staticMethods = staticMethods
+	o.stmt = stmt
+}
+
+func (o *Optimizer) visitReturnStmt(stmt ReturnStmt) {
+	stmt.value = o.expression(stmt.value)
+	o.stmt = stmt
+}
+
+func (o *Optimizer) visitFunStmt(stmt FunStmt) {
+	o.stmt = o.function(stmt)
+}
+
+func (o *Optimizer) visitIfStmt(stmt IfStmt) {
+	condition := o.expression(stmt.condition)
+	if literal, ok := condition.(LiteralExpr); ok {
+		// the branch taken runs in the scope the if statement ran in, like it did before
+		if toBool(literal.value) {
+			o.stmt = o.statement(stmt.consequent)
+		} else if stmt.alternate != nil {
+			o.stmt = o.statement(stmt.alternate)
+		} else {
+			o.stmt = nil
+		}
+		return
+	}
+
+	var alternate Stmt
+	if stmt.alternate != nil {
+		alternate = o.statement(stmt.alternate)
+	}
+	o.stmt = IfStmt{condition, o.branch(stmt.consequent), alternate}
+}
+
+func (o *Optimizer) visitWhileStmt(stmt WhileStmt) {
+	condition := o.expression(stmt.condition)
+	if literal, ok := condition.(LiteralExpr); ok && !toBool(literal.value) {
+		o.stmt = nil
+		return
+	}
+	o.stmt = WhileStmt{condition, o.branch(stmt.body), o.expression(stmt.increment), stmt.label}
+}
+
+func (o *Optimizer) visitForInStmt(stmt ForInStmt) {
+	stmt.iterable = o.expression(stmt.iterable)
+	stmt.body = o.branch(stmt.body)
+	o.stmt = stmt
+}
+
+func (o *Optimizer) visitThrowStmt(stmt ThrowStmt) {
+	stmt.value = o.expression(stmt.value)
+	o.stmt = stmt
+}
+
+func (o *Optimizer) visitTryStmt(stmt TryStmt) {
+	stmt.body = o.block(stmt.body)
+	if stmt.catchBody != nil {
+		catchBody := o.block(*stmt.catchBody)
+		stmt.catchBody = &catchBody
+	}
+	if stmt.finallyBody != nil {
+		finallyBody := o.block(*stmt.finallyBody)
+		stmt.finallyBody = &finallyBody
+	}
+	o.stmt = stmt
+}
+
+func (o *Optimizer) visitImportStmt(stmt ImportStmt) {
+	o.stmt = stmt
+}
+
+func (o *Optimizer) visitExportStmt(stmt ExportStmt) {
+	stmt.declaration = o.statement(stmt.declaration)
+	o.stmt = stmt
+}
+
+func (o *Optimizer) visitBreakStmt(stmt BreakStmt) {
+	o.stmt = stmt
+}
+
+func (o *Optimizer) visitContinueStmt(stmt ContinueStmt) {
+	o.stmt = stmt
+}
+
+/* expressions, each visit returns the rewritten Expr */
+
+func (o *Optimizer) visitBinaryExpr(expr BinaryExpr) interface{} {
+	expr.left = o.expression(expr.left)
+	expr.right = o.expression(expr.right)
+	left, leftOk := expr.left.(LiteralExpr)
+	right, rightOk := expr.right.(LiteralExpr)
+	if leftOk && rightOk {
+		if value, ok := fold(func() interface{} {
+			return binary(expr.operator, left.value, right.value)
+		}); ok {
+			return LiteralExpr{value}
+		}
+	}
+	return expr
+}
+
+func (o *Optimizer) visitUnaryExpr(expr UnaryExpr) interface{} {
+	expr.right = o.expression(expr.right)
+	if right, ok := expr.right.(LiteralExpr); ok {
+		if value, ok := fold(func() interface{} {
+			return unary(expr.operator, right.value)
+		}); ok {
+			return LiteralExpr{value}
+		}
+	}
+	return expr
+}
+
+func (o *Optimizer) visitLogicalExpr(expr LogicalExpr) interface{} {
+	expr.left = o.expression(expr.left)
+	expr.right = o.expression(expr.right)
+	left, ok := expr.left.(LiteralExpr)
+	if !ok {
+		return expr
+	}
+	// short-circuit like visitLogicalExpr, the result is an operand and not a bool
+	if expr.operator.tokentype == OR && toBool(left.value) ||
+		expr.operator.tokentype == AND && !toBool(left.value) {
+		return left
+	}
+	return expr.right
+}
+
+func (o *Optimizer) visitConditionExpr(expr ConditionExpr) interface{} {
+	expr.test = o.expression(expr.test)
+	expr.consequent = o.expression(expr.consequent)
+	expr.alternate = o.expression(expr.alternate)
+	if test, ok := expr.test.(LiteralExpr); ok {
+		if toBool(test.value) {
+			return expr.consequent
+		}
+		return expr.alternate
+	}
+	return expr
+}
+
+func (o *Optimizer) visitGroupingExpr(expr GroupingExpr) interface{} {
+	expr.expression = o.expression(expr.expression)
+	if literal, ok := expr.expression.(LiteralExpr); ok {
+		return literal
+	}
+	return expr
+}
+
+func (o *Optimizer) visitLiteralExpr(expr LiteralExpr) interface{} {
+	return expr
+}
+
+func (o *Optimizer) visitSequenceExpr(expr SequenceExpr) interface{} {
+	expr.exprs = o.expressions(expr.exprs)
+	return expr
+}
+
+func (o *Optimizer) visitAssignExpr(expr AssignExpr) interface{} {
+	expr.right = o.expression(expr.right)
+	return expr
+}
+
+func (o *Optimizer) visitIdentifierExpr(expr IdentifierExpr) interface{} {
+	return expr
+}
+
+func (o *Optimizer) visitCallExpr(expr CallExpr) interface{} {
+	expr.callee = o.expression(expr.callee)
+	expr.arguments = o.expressions(expr.arguments)
+	return expr
+}
+
+func (o *Optimizer) visitFunExpr(expr FunExpr) interface{} {
+	expr.body = o.block(expr.body)
+	return expr
+}
+
+func (o *Optimizer) visitGetExpr(expr GetExpr) interface{} {
+	expr.object = o.expression(expr.object)
+	return expr
+}
+
+func (o *Optimizer) visitSetExpr(expr SetExpr) interface{} {
+	expr.object = o.expression(expr.object)
+	expr.value = o.expression(expr.value)
+	return expr
+}
+
+func (o *Optimizer) visitThisExpr(expr ThisExpr) interface{} {
+	return expr
+}
+
+func (o *Optimizer) visitSuperExpr(expr SuperExpr) interface{} {
+	return expr
+}
+
+func (o *Optimizer) visitInterpolationExpr(expr InterpolationExpr) interface{} {
+	expr.parts = o.expressions(expr.parts)
+	return expr
+}
+
+func (o *Optimizer) visitListExpr(expr ListExpr) interface{} {
+	expr.elements = o.expressions(expr.elements)
+	return expr
+}
+
+func (o *Optimizer) visitIndexExpr(expr IndexExpr) interface{} {
+	expr.object = o.expression(expr.object)
+	expr.index = o.expression(expr.index)
+	return expr
+}
+
+func (o *Optimizer) visitIndexSetExpr(expr IndexSetExpr) interface{} {
+	expr.object = o.expression(expr.object)
+	expr.index = o.expression(expr.index)
+	expr.value = o.expression(expr.value)
+	return expr
+}
+
+func (o *Optimizer) visitMapExpr(expr MapExpr) interface{} {
+	expr.keys = o.expressions(expr.keys)
+	expr.values = o.expressions(expr.values)
+	return expr
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOptimizer(t *testing.T) {
+	runtime, _ := NewRuntime(Options{})
+	stmts := runtime.lox.compile("test.lox", `
+		print(1 + 2 * 3, "n" + 1.5, -(2), !nil, -0);
+		print(1 + nil);
+		print(nil or "x", 0 and 2, false or nil, "a" and "b");
+		if (1 < 2) print("yes"); else print("no");
+		while (false) print("never");
+		var x = 1;
+		print(x + 2 * 3);
+	`)
+	var out bytes.Buffer
+	(&AstPrinter{out: &out}).printStmts(stmts)
+	// `1 + nil` is left for the program to raise, `and` and `or` fold to one of their operands
+	want := `print(7, "n1.5", -2, true, -0);
+print((1 + nil));
+print("x", 2, nil, "b");
+print("yes");
+var x = 1;
+print((x + 6));
+`
+	if out.String() != want {
+		t.Errorf("optimized to\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestFoldedProgramsRunTheSame(t *testing.T) {
+	expectPrints(t, `print(nil or "x", 0 and 2, false or nil, "a" and "b", 1 / -0, "n" + 1.5);`, "x 2 <nil> b -Inf n1.5\n")
+
+	// an operation that can't be folded still fails where it is written
+	for _, vm := range []bool{false, true} {
+		_, err := runOn(vm, "print(1);\nprint(2 * 3 + nil);")
+		if lerr, ok := err.(*Error); !ok || lerr.Line != 2 || lerr.Column != 13 {
+			t.Errorf("vm %v: 2 * 3 + nil failed with %v", vm, err)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package core
 
 import "fmt"
 
@@ -45,16 +45,65 @@ func (p *Parser) checkType(tokentype TokenType) bool {
 	return p.peek().tokentype == tokentype
 }
 
-func (p *Parser) consume(tokentype TokenType, msg string) {
+// consume advances past the expected token,
+// otherwise it reports and panics, which unwinds the parser back to declaration
+func (p *Parser) consume(tokentype TokenType, msg string) Token {
 	if p.checkType(tokentype) {
+		return p.advance()
+	}
+
+	panic(p.error(p.peek(), msg))
+}
+
+// error reports a syntax error and returns it,
+// callers panic with it when the parser can't make sense of the following tokens
+func (p *Parser) error(token Token, msg string) ParseError {
+	err := ParseError{token, msg}
+	p.lox.errorReporter.report(err)
+	return err
+}
+
+// synchronize discards tokens until it reaches a statement boundary,
+// so the errors reported afterwards are not caused by the previous one.
+// start is where the broken declaration began, blocks it opened are skipped as a whole.
+func (p *Parser) synchronize(start int) {
+	depth := 0
+	for _, token := range p.tokens[start:p.current] {
+		if token.tokentype == LEFT_BRACE {
+			depth++
+		} else if token.tokentype == RIGHT_BRACE {
+			depth--
+		}
+	}
+
+	// a declaration that is broken from its very first token still has to make progress
+	if p.current == start {
 		p.advance()
-		return
 	}
 
-	p.lox.errorReporter.errorWithoutExit(ParseError{
-		p.peek(),
-		msg,
-	})
+	for !p.isAtEnd() {
+		if depth == 0 {
+			switch p.peek().tokentype {
+			// leave '}' to the enclosing block
+			case CLASS, FUN, VAR, FOR, IF, WHILE, RETURN, THROW, TRY, IMPORT, EXPORT, RIGHT_BRACE:
+				return
+			}
+		}
+
+		switch p.advance().tokentype {
+		case LEFT_BRACE:
+			depth++
+		case RIGHT_BRACE:
+			depth--
+			if depth == 0 {
+				return
+			}
+		case SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+	}
 }
 
 func (p *Parser) isAtEnd() bool {
@@ -71,6 +120,14 @@ func (p *Parser) peek() Token {
 	return p.tokens[p.current]
 }
 
+// peekNext returns the token after peek
+func (p *Parser) peekNext() Token {
+	if p.isAtEnd() {
+		return p.peek()
+	}
+	return p.tokens[p.current+1]
+}
+
 // advance comsume A token and returns it
 func (p *Parser) advance() Token {
 	if !p.isAtEnd() {
@@ -82,7 +139,9 @@ func (p *Parser) advance() Token {
 func (p *Parser) parse() []Stmt {
 	statements := make([]Stmt, 0)
 	for !p.isAtEnd() {
-		statements = append(statements, p.declaration())
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
 	}
 
 	return statements
@@ -105,21 +164,74 @@ func (p *Parser) reset() {
 
 /* STATEMENTS */
 
-func (p *Parser) declaration() Stmt {
+// declaration is where the parser recovers from syntax errors, a broken declaration returns nil
+func (p *Parser) declaration() (stmt Stmt) {
+	start := p.current
+	defer func() {
+		if err := recover(); err != nil {
+			if _, ok := err.(ParseError); !ok {
+				panic(err)
+			}
+			p.synchronize(start)
+			stmt = nil
+		}
+	}()
+
 	if p.match(VAR) {
 		return p.varDeclaration()
 	}
 	if p.match(FUN) {
-		return p.functionDeclaration("function")
+		return p.functionDeclaration("function", p.previous().doc)
 	}
 	if p.match(CLASS) {
 		return p.classDeclaration()
 	}
+	if p.match(IMPORT) {
+		return p.importDeclaration()
+	}
+	if p.match(EXPORT) {
+		return p.exportDeclaration()
+	}
 
 	return p.statement()
 }
 
+// importDecl → "import" STRING "as" IDENTIFIER ";" ;
+func (p *Parser) importDeclaration() Stmt {
+	keyword := p.previous()
+	path := p.consume(STRING, "Expect a module path after import")
+	p.consume(AS, "Expect 'as' after module path")
+	name := p.consume(IDENTIFIER, "Expect a name for the module after 'as'")
+	p.consume(SEMICOLON, "Unexpected end of input, Expect ';' after import")
+
+	return ImportStmt{keyword, path, name}
+}
+
+// exportDecl → "export" ( varDecl | funDecl | classDecl ) ;
+func (p *Parser) exportDeclaration() Stmt {
+	keyword := p.previous()
+	// doc comments written before `export` belong to the declaration
+	if !p.isAtEnd() && p.peek().doc == "" {
+		p.tokens[p.current].doc = keyword.doc
+	}
+
+	var declaration Stmt
+	if p.match(VAR) {
+		declaration = p.varDeclaration()
+	} else if p.match(FUN) {
+		declaration = p.functionDeclaration("function", p.previous().doc)
+	} else if p.match(CLASS) {
+		declaration = p.classDeclaration()
+	} else {
+		panic(p.error(p.peek(), "Expect a var, fun or class declaration after export"))
+	}
+
+	return ExportStmt{keyword, declaration}
+}
+
+// varDeclaration is called right after 'var', which carries the doc comments
 func (p *Parser) varDeclaration() Stmt {
+	doc := p.previous().doc
 	p.consume(IDENTIFIER, "Unexpected token")
 	name := p.previous()
 	var init Expr
@@ -127,11 +239,12 @@ func (p *Parser) varDeclaration() Stmt {
 		init = p.expression()
 	}
 	p.consume(SEMICOLON, "Unexpected end of input, Expect ';' after value")
-	return VarStmt{name, init}
+	return VarStmt{name, init, doc}
 }
 
 // classDecl → "class" IDENTIFIER "{" function* "}" ;
 func (p *Parser) classDeclaration() Stmt {
+	doc := p.previous().doc
 	p.consume(IDENTIFIER, "class statements require a class name")
 	name := p.previous()
 
@@ -148,18 +261,21 @@ func (p *Parser) classDeclaration() Stmt {
 	staticMethods := make([]FunStmt, 0)
 
 	for !p.checkType(RIGHT_BRACE) && !p.isAtEnd() {
+		// doc comments are on 'static' or on the method name
+		doc := p.peek().doc
 		if p.match(STATIC) {
-			staticMethods = append(staticMethods, p.functionDeclaration("static method"))
+			staticMethods = append(staticMethods, p.functionDeclaration("static method", doc))
+		} else {
+			methods = append(methods, p.functionDeclaration("method", doc))
 		}
-		methods = append(methods, p.functionDeclaration("method"))
 	}
 
 	p.consume(RIGHT_BRACE, "Expect '}' after class body.")
 
-	return ClassStmt{name, super, methods, staticMethods}
+	return ClassStmt{name, super, methods, staticMethods, doc}
 }
 
-func (p *Parser) functionDeclaration(kind string) FunStmt {
+func (p *Parser) functionDeclaration(kind string, doc string) FunStmt {
 	p.consume(IDENTIFIER, "Function statements require a function name")
 	name := p.previous()
 	p.consume(LEFT_PAREN, "Expect '(' after "+kind+" name.")
@@ -174,10 +290,7 @@ func (p *Parser) functionDeclaration(kind string) FunStmt {
 
 	for p.match(COMMA) {
 		if len(params) >= 255 {
-			p.lox.errorReporter.errorWithoutExit(ParseError{
-				p.peek(),
-				"Cannot have more than 255 arguments",
-			})
+			p.error(p.peek(), "Cannot have more than 255 arguments")
 		}
 
 		p.consume(IDENTIFIER, "Expect parameter name")
@@ -189,7 +302,7 @@ func (p *Parser) functionDeclaration(kind string) FunStmt {
 
 	body := p.blockStatement()
 
-	return FunStmt{name, params, body}
+	return FunStmt{name, params, body, doc}
 }
 
 func (p *Parser) statement() Stmt {
@@ -206,15 +319,61 @@ func (p *Parser) statement() Stmt {
 		return p.ifstatement()
 	}
 	if p.match(WHILE) {
-		return p.whileStatement()
+		return p.whileStatement(Token{})
 	}
 	if p.match(FOR) {
-		return p.forStatement()
+		return p.forStatement(Token{})
+	}
+	if p.match(THROW) {
+		return p.throwStatement()
+	}
+	if p.match(TRY) {
+		return p.tryStatement()
+	}
+	if p.match(BREAK) {
+		return p.breakStatement()
+	}
+	if p.match(CONTINUE) {
+		return p.continueStatement()
+	}
+	// labeled loop like `outer: while (...)`
+	if p.checkType(IDENTIFIER) && p.peekNext().tokentype == COLON {
+		label := p.advance()
+		p.advance()
+		if p.match(WHILE) {
+			return p.whileStatement(label)
+		}
+		if p.match(FOR) {
+			return p.forStatement(label)
+		}
+		panic(p.error(p.peek(), "Expect a loop after label '"+label.literal+"'"))
 	}
 
 	return p.expressionStatement()
 }
 
+// breakStmt → "break" IDENTIFIER? ";" ;
+func (p *Parser) breakStatement() Stmt {
+	keyword := p.previous()
+	var label Token
+	if p.match(IDENTIFIER) {
+		label = p.previous()
+	}
+	p.consume(SEMICOLON, "Unexpected end of input, Expect ';' after break")
+	return BreakStmt{keyword, label}
+}
+
+// continueStmt → "continue" IDENTIFIER? ";" ;
+func (p *Parser) continueStatement() Stmt {
+	keyword := p.previous()
+	var label Token
+	if p.match(IDENTIFIER) {
+		label = p.previous()
+	}
+	p.consume(SEMICOLON, "Unexpected end of input, Expect ';' after continue")
+	return ContinueStmt{keyword, label}
+}
+
 // func (p *Parser) printStatement() Stmt {
 // 	expr := p.expression()
 // 	p.consume(SEMICOLON, "Unexpected end of input, Expect ';' after value")
@@ -233,6 +392,43 @@ func (p *Parser) returnStatement() Stmt {
 	return ReturnStmt{keyword, returnVal}
 }
 
+// throwStmt → "throw" expression ";" ;
+func (p *Parser) throwStatement() Stmt {
+	keyword := p.previous()
+	value := p.expression()
+	p.consume(SEMICOLON, "Unexpected end of input, Expect ';' after thrown value")
+
+	return ThrowStmt{keyword, value}
+}
+
+// tryStmt → "try" block ( "catch" "(" IDENTIFIER ")" block )? ( "finally" block )? ;
+// at least one of catch and finally must be there
+func (p *Parser) tryStatement() Stmt {
+	p.consume(LEFT_BRACE, "Expect '{' after try")
+	body := p.blockStatement()
+
+	var name Token
+	var catchBody, finallyBody *BlockStmt
+	if p.match(CATCH) {
+		p.consume(LEFT_PAREN, "Expect '(' after catch")
+		name = p.consume(IDENTIFIER, "Expect error variable name")
+		p.consume(RIGHT_PAREN, "Expect ')' after error variable name")
+		p.consume(LEFT_BRACE, "Expect '{' after catch clause")
+		block := p.blockStatement()
+		catchBody = &block
+	}
+	if p.match(FINALLY) {
+		p.consume(LEFT_BRACE, "Expect '{' after finally")
+		block := p.blockStatement()
+		finallyBody = &block
+	}
+	if catchBody == nil && finallyBody == nil {
+		panic(p.error(p.peek(), "Missing catch or finally after try"))
+	}
+
+	return TryStmt{body, name, catchBody, finallyBody}
+}
+
 func (p *Parser) ifstatement() Stmt {
 	p.consume(LEFT_PAREN, "Unexpected token")
 	condition := p.expression()
@@ -248,12 +444,13 @@ func (p *Parser) ifstatement() Stmt {
 	return IfStmt{condition, consequent, alternate}
 }
 
-func (p *Parser) whileStatement() Stmt {
+// whileStatement parses the loop after 'while', label is empty for unlabeled loops
+func (p *Parser) whileStatement(label Token) Stmt {
 	p.consume(LEFT_PAREN, "Unexpected token")
 	condition := p.expression()
 	p.consume(RIGHT_PAREN, "Unexpected token")
 	body := p.statement()
-	return WhileStmt{condition, body}
+	return WhileStmt{condition, body, nil, label}
 }
 
 /*
@@ -265,8 +462,10 @@ func (p *Parser) whileStatement() Stmt {
 			i = i + 1; 			// increment
 		}
 	}
+	where the increment is a field of the while loop rather than part of its body,
+	because `continue` must not skip it.
 */
-func (p *Parser) forStatement() Stmt {
+func (p *Parser) forStatement(label Token) Stmt {
 	// forStmt is syntactic sugar for while loop
 	p.consume(LEFT_PAREN, "Expected ')' before for clauses")
 
@@ -278,6 +477,9 @@ func (p *Parser) forStatement() Stmt {
 	if p.match(SEMICOLON) {
 		init = nil
 	} else if p.match(VAR) {
+		if p.checkType(IDENTIFIER) && p.peekNext().tokentype == IN {
+			return p.forInStatement(label)
+		}
 		init = p.varDeclaration()
 	} else {
 		init = p.expressionStatement()
@@ -301,15 +503,9 @@ func (p *Parser) forStatement() Stmt {
 
 	body = p.statement()
 
-	if increment != nil {
-		// add increment expr to the end of for original boby
-		// which will be executed after original body is executed
-		body = BlockStmt{
-			[]Stmt{body, ExpressionStmt{increment}},
-		}
-	}
-
-	body = WhileStmt{condition, body}
+	// the increment is kept apart from the original body,
+	// so it is still executed when `continue` skips the rest of the body
+	body = WhileStmt{condition, body, increment, label}
 
 	if init != nil {
 		// add initializer stmt to the beginning of the while boby
@@ -321,6 +517,17 @@ func (p *Parser) forStatement() Stmt {
 	return body
 }
 
+// forInStmt → "for" "(" "var" IDENTIFIER "in" expression ")" statement ;
+func (p *Parser) forInStatement(label Token) Stmt {
+	name := p.advance()
+	keyword := p.advance()
+	iterable := p.expression()
+	p.consume(RIGHT_PAREN, "Expected ')' after for-in clauses")
+	body := p.statement()
+
+	return ForInStmt{name, keyword, iterable, body, label}
+}
+
 func (p *Parser) expressionStatement() Stmt {
 	expr := p.expression()
 	p.consume(SEMICOLON, "Unexpected end of input, Expect ';' after value")
@@ -330,7 +537,9 @@ func (p *Parser) expressionStatement() Stmt {
 func (p *Parser) blockStatement() BlockStmt {
 	stmts := make([]Stmt, 0)
 	for !p.checkType(RIGHT_BRACE) && !p.isAtEnd() {
-		stmts = append(stmts, p.declaration())
+		if stmt := p.declaration(); stmt != nil {
+			stmts = append(stmts, stmt)
+		}
 	}
 	p.consume(RIGHT_BRACE, "Unexpected end of input, Expect '}' after block")
 	return BlockStmt{stmts}
@@ -376,10 +585,11 @@ func (p *Parser) assignment() Expr {
 		if expr, ok := expr.(GetExpr); ok {
 			return SetExpr{expr.object, expr.name, value}
 		}
-		p.lox.errorReporter.errorWithoutExit(RuntimeError{
-			equal,
-			"Invalid left-hand assignment target.",
-		})
+		if expr, ok := expr.(IndexExpr); ok {
+			return IndexSetExpr{expr.object, expr.bracket, expr.index, value}
+		}
+		// no need to synchronize, the parser is not confused
+		p.error(equal, "Invalid left-hand assignment target.")
 	}
 	return expr
 }
@@ -390,18 +600,12 @@ func (p *Parser) condition() Expr {
 
 	if p.match(QUESTION) {
 		consequent := p.condition()
-		if p.match(COLON) {
-			alternate := p.condition()
-			expr = ConditionExpr{
-				expr,
-				consequent,
-				alternate,
-			}
-		} else {
-			p.lox.errorReporter.errorWithoutExit(ParseError{
-				p.peek(),
-				"Unexpected end of input",
-			})
+		p.consume(COLON, "Expect ':' in conditional expression")
+		alternate := p.condition()
+		expr = ConditionExpr{
+			expr,
+			consequent,
+			alternate,
 		}
 	}
 	return expr
@@ -498,7 +702,7 @@ func (p *Parser) unary() Expr {
 	return p.call()
 }
 
-// call → primary ( "(" sequence? ")" | "." IDENTIFIER )* ;
+// call → primary ( "(" sequence? ")" | "." IDENTIFIER | "[" expression "]" )* ;
 func (p *Parser) call() Expr {
 	expr := p.primary()
 
@@ -511,6 +715,12 @@ func (p *Parser) call() Expr {
 			p.consume(IDENTIFIER, "Expect property name after '.'.")
 			name := p.previous()
 			expr = GetExpr{expr, name}
+		} else if p.match(LEFT_BRACKET) {
+			// handle index grammer: `a[1][2]`
+			bracket := p.previous()
+			index := p.expression()
+			p.consume(RIGHT_BRACKET, "Expect ']' after index.")
+			expr = IndexExpr{expr, bracket, index}
 		} else {
 			break
 		}
@@ -533,10 +743,7 @@ func (p *Parser) finishCall(callee Expr) Expr {
 	}
 
 	if len(args) >= 255 {
-		p.lox.errorReporter.errorWithoutExit(ParseError{
-			p.peek(),
-			"Cannot have more than 255 arguments",
-		})
+		p.error(p.peek(), "Cannot have more than 255 arguments")
 	}
 	p.consume(RIGHT_PAREN, "Expect ')' after arguments.")
 
@@ -548,6 +755,7 @@ func (p *Parser) finishCall(callee Expr) Expr {
 // func → "fun" IDENTIFIER? "(" parameters? ")" block ;
 // parameters → IDENTIFIER ( "," IDENTIFIER )* ;
 func (p *Parser) functionExpr() Expr {
+	keyword := p.previous()
 	if p.checkType(IDENTIFIER) {
 		p.advance()
 	}
@@ -563,10 +771,7 @@ func (p *Parser) functionExpr() Expr {
 
 	for p.match(COMMA) {
 		if len(params) >= 255 {
-			p.lox.errorReporter.errorWithoutExit(ParseError{
-				p.peek(),
-				"Cannot have more than 255 arguments",
-			})
+			p.error(p.peek(), "Cannot have more than 255 arguments")
 		}
 
 		p.consume(IDENTIFIER, "Expect parameter name")
@@ -578,7 +783,59 @@ func (p *Parser) functionExpr() Expr {
 
 	body := p.blockStatement()
 
-	return FunExpr{params, body}
+	return FunExpr{keyword, params, body}
+}
+
+// interpolation → INTERPOLATION expression ( INTERPOLATION expression )* STRING ;
+// the string parts and the interpolated expressions alternate, "a ${b} c" becomes "a ", b, " c"
+func (p *Parser) interpolation() Expr {
+	parts := []Expr{LiteralExpr{p.previous().lexeme}}
+	for {
+		parts = append(parts, p.expression())
+		if p.match(INTERPOLATION) {
+			parts = append(parts, LiteralExpr{p.previous().lexeme})
+			continue
+		}
+		p.consume(STRING, "Expect '}' after interpolated expression")
+		parts = append(parts, LiteralExpr{p.previous().lexeme})
+		return InterpolationExpr{parts}
+	}
+}
+
+// list → "[" ( assignment ( "," assignment )* ","? )? "]" ;
+func (p *Parser) list() Expr {
+	bracket := p.previous()
+	elements := make([]Expr, 0)
+
+	for !p.checkType(RIGHT_BRACKET) && !p.isAtEnd() {
+		elements = append(elements, p.assignment())
+		if !p.match(COMMA) {
+			break
+		}
+	}
+	p.consume(RIGHT_BRACKET, "Expect ']' after list elements.")
+
+	return ListExpr{bracket, elements}
+}
+
+// map → "{" ( entry ( "," entry )* ","? )? "}" ;
+// entry → assignment ":" assignment ;
+func (p *Parser) mapLiteral() Expr {
+	brace := p.previous()
+	keys := make([]Expr, 0)
+	values := make([]Expr, 0)
+
+	for !p.checkType(RIGHT_BRACE) && !p.isAtEnd() {
+		keys = append(keys, p.assignment())
+		p.consume(COLON, "Expect ':' after map key.")
+		values = append(values, p.assignment())
+		if !p.match(COMMA) {
+			break
+		}
+	}
+	p.consume(RIGHT_BRACE, "Expect '}' after map entries.")
+
+	return MapExpr{brace, keys, values}
 }
 
 // unary rule
@@ -595,6 +852,16 @@ func (p *Parser) primary() Expr {
 	if p.match(NUMBER, STRING) {
 		return LiteralExpr{p.previous().lexeme}
 	}
+	if p.match(INTERPOLATION) {
+		return p.interpolation()
+	}
+	if p.match(LEFT_BRACKET) {
+		return p.list()
+	}
+	// statement() takes '{' as a block first, so here it can only start a map
+	if p.match(LEFT_BRACE) {
+		return p.mapLiteral()
+	}
 	if p.match(IDENTIFIER, STRING) {
 		return IdentifierExpr{p.previous()}
 	}
@@ -612,19 +879,9 @@ func (p *Parser) primary() Expr {
 	}
 	if p.match(LEFT_PAREN) {
 		expr := p.expression()
-		if !p.match(RIGHT_PAREN) {
-			p.lox.errorReporter.errorWithoutExit(ParseError{
-				p.peek(),
-				"Unexpected end of input",
-			})
-		}
+		p.consume(RIGHT_PAREN, "Expect ')' after expression")
 		return GroupingExpr{expr}
 	}
 
-	p.lox.errorReporter.error(ParseError{
-		p.peek(),
-		"Invalid or unexpected token:" + p.peek().literal,
-	})
-
-	return nil
+	panic(p.error(p.peek(), "Invalid or unexpected token: "+p.peek().literal))
 }
@@ -0,0 +1,25 @@
+package core
+
+import "testing"
+
+func TestParserRecovers(t *testing.T) {
+	// every broken statement is reported once, and the ones after it are still parsed
+	diagnostics := compileDiagnostics("print(\"x\");\nvar a = 1 +;\nfun f( { return 1; }\nclass { }\nprint(2);\nvar b = 2")
+	want := []struct {
+		line int
+		msg  string
+	}{
+		{2, "Invalid or unexpected token: ;"},
+		{3, "Expect parameter name"},
+		{4, "class statements require a class name"},
+		{6, "Unexpected end of input, Expect ';' after value"},
+	}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("reported %v", diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.code != CodeSyntax || d.span.line != want[i].line || d.msg != want[i].msg {
+			t.Errorf("diagnostic %d is %v, want line %d: %s", i, d, want[i].line, want[i].msg)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// replFilename names the REPL input in diagnostics
+	replFilename       = "<stdin>"
+	prompt             = "> "
+	continuationPrompt = "... "
+)
+
+func (l *Lox) runREPL(in io.Reader) {
+	l.filename = replFilename
+	scanner := bufio.NewScanner(in)
+	var input []string
+
+	fmt.Fprint(l.stdout, prompt)
+	for scanner.Scan() {
+		line := scanner.Text()
+		input = append(input, line)
+		src := strings.Join(input, "\n")
+
+		// keep asking for lines until the input is complete,
+		// an empty line gives up and lets the parser report what is wrong
+		if !isComplete(src) && strings.TrimSpace(line) != "" {
+			fmt.Fprint(l.stdout, continuationPrompt)
+			continue
+		}
+
+		l.runInput(src)
+		l.release(-1)
+		// diagnostics are rendered already, a limit stopping the input is not one
+		if err := l.takeError(); err != nil {
+			if _, ok := err.(*Error); !ok {
+				fmt.Fprintln(l.stderr, "[GLOX]:", err)
+			}
+		}
+		input = input[:0]
+		fmt.Fprint(l.stdout, prompt)
+	}
+
+	if scanner.Err() != nil {
+		fmt.Fprintln(l.stdout, "[GLOX]: failed to read input:", scanner.Err())
+	}
+}
+
+// runInput runs a complete REPL input on the engine programs run on, a bare expression statement gets its value echoed
+func (l *Lox) runInput(src string) {
+	l.limits.start(context.Background())
+	stmts := l.compile(l.filename, src)
+	if l.hasError {
+		return
+	}
+
+	var echo Expr
+	if len(stmts) == 1 {
+		if stmt, ok := stmts[0].(ExpressionStmt); ok {
+			echo = stmt.expression
+			stmts = nil
+		}
+	}
+
+	var value interface{}
+	if l.vm != nil {
+		function := l.compileEval(stmts, echo)
+		if l.hasError {
+			return
+		}
+		l.interpret(func() {
+			value = l.vm.interpret(function)
+		})
+	} else {
+		l.interpret(func() {
+			l.interpreter.executeBlock(stmts)
+			if echo != nil {
+				value = l.interpreter.evaluate(echo)
+			}
+		})
+	}
+
+	// nil is not worth echoing, it is what calls like `print(a);` produce
+	if echo != nil && value != nil {
+		fmt.Fprintln(l.stdout, stringify(value))
+	}
+}
+
+// isComplete reports whether src can be handed to the parser,
+// which is not the case while a string or a block comment is left open,
+// a bracket is left unbalanced or the last statement lacks its ';' or '}'.
+func isComplete(src string) bool {
+	runes := []rune(src)
+	depth := 0
+	// interpolations holds the bracket depth each unfinished `${` was opened at
+	var interpolations []int
+	inString := false
+	var last rune
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if inString {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+				last = c
+			} else if c == '$' && i+1 < len(runes) && runes[i+1] == '{' {
+				interpolations = append(interpolations, depth)
+				inString = false
+				i++
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+		case c == '}' && len(interpolations) > 0 && interpolations[len(interpolations)-1] == depth:
+			// the end of an interpolation, back into its string
+			interpolations = interpolations[:len(interpolations)-1]
+			inString = true
+			continue
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// block comments nest
+			nested := 1
+			for i += 2; i+1 < len(runes) && nested > 0; i++ {
+				if runes[i] == '/' && runes[i+1] == '*' {
+					nested++
+					i++
+				} else if runes[i] == '*' && runes[i+1] == '/' {
+					nested--
+					i++
+				}
+			}
+			if nested > 0 {
+				return false
+			}
+			// the loop already stepped past the closing '/'
+			i--
+			continue
+		case c == '(' || c == '{' || c == '[':
+			depth++
+		case c == ')' || c == '}' || c == ']':
+			depth--
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		}
+		last = c
+	}
+
+	if inString || len(interpolations) > 0 || depth > 0 {
+		return false
+	}
+	// unbalanced closing brackets can never be completed, leave them to the parser
+	return depth < 0 || last == 0 || last == ';' || last == '}'
+}
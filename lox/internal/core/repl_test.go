@@ -0,0 +1,24 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	for _, vm := range []bool{false, true} {
+		var out bytes.Buffer
+		runtime, _ := NewRuntime(Options{VM: vm, Stdout: &out})
+		runtime.REPL(strings.NewReader("fun add(a,\n b) { return a + b; }\nadd(1, 2);\nprint(add);\nnil;\n"))
+		if want := "> ... > 3\n> <fn add>\n> > "; out.String() != want {
+			t.Errorf("vm %v: the REPL printed %q, want %q", vm, out.String(), want)
+		}
+
+		// functions are closures when the VM runs them
+		_, closure := runtime.lox.interpreter.global.values["add"].(*Closure)
+		if closure != vm {
+			t.Errorf("vm %v: the REPL made add a %T", vm, runtime.lox.interpreter.global.values["add"])
+		}
+	}
+}
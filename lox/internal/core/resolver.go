@@ -1,9 +1,32 @@
-package main
+package core
+
+import "fmt"
+
+// ResolveError is a semantic error found by the resolver
+type ResolveError struct {
+	token Token
+	msg   string
+}
+
+func (e ResolveError) Error() string {
+	return fmt.Sprintf("[GLOX] ResolveError: Line %d, Column %d, %s", e.token.line, e.token.column, e.msg)
+}
+
+// variable is a local the resolver knows about
+type variable struct {
+	// slot is where the variable lives in its environment at runtime, locals are numbered in declaration order
+	slot int
+	// defined is false until the initializer of the variable has been resolved
+	defined bool
+}
+
+// scope holds the locals declared in a block
+type scope map[string]*variable
 
 // stack based on slice
-type scopes []map[string]bool
+type scopes []scope
 
-func (s scopes) peek() map[string]bool {
+func (s scopes) peek() scope {
 	return s[len(s)-1]
 }
 
@@ -53,6 +76,8 @@ type Resolver struct {
 	currentFunction functionType
 	// currentClass shows if we are visiting a class statement
 	currentClass classType
+	// loops holds the labels of the loops we are in, "" for unlabeled ones
+	loops []string
 }
 
 // NewResolver create a Resolver instance
@@ -63,6 +88,7 @@ func NewResolver(l *Lox, interpreter *Interpreter) Resolver {
 		make(scopes, 0),
 		NONE,
 		NONECLASS,
+		nil,
 	}
 }
 
@@ -89,6 +115,8 @@ func (r Resolver) visitFunStmt(stmt FunStmt) {
 func (r Resolver) visitFunExpr(expr FunExpr) interface{} {
 	parentFunctionType := r.currentFunction
 	r.currentFunction = FUNCTION
+	// break and continue can't cross function boundaries
+	r.loops = nil
 
 	// like the blockStatement
 	r.scopes = r.beginScope()
@@ -106,6 +134,8 @@ func (r Resolver) visitFunExpr(expr FunExpr) interface{} {
 func (r Resolver) resolveFunction(stmt FunStmt, ftype functionType) {
 	parentFunctionType := r.currentFunction
 	r.currentFunction = ftype
+	// break and continue can't cross function boundaries
+	r.loops = nil
 
 	// like the blockStatement
 	r.scopes = r.beginScope()
@@ -123,7 +153,7 @@ func (r Resolver) resolveFunction(stmt FunStmt, ftype functionType) {
 
 func (r Resolver) beginScope() scopes {
 	// add A new scope for block
-	return append(r.scopes, make(map[string]bool, 0))
+	return append(r.scopes, make(scope, 0))
 }
 
 func (r Resolver) endScope() {
@@ -147,7 +177,7 @@ func (r Resolver) visitClassStmt(stmt ClassStmt) {
 	if stmt.super != nil {
 		r.currentClass = SUBCLASS
 		if stmt.super.name.literal == stmt.name.literal {
-			r.lox.errorReporter.error(ParseError{
+			r.lox.errorReporter.report(ResolveError{
 				stmt.super.name,
 				"Cannot Access '" + stmt.super.name.literal + "' before initialization",
 			})
@@ -166,12 +196,12 @@ func (r Resolver) visitClassStmt(stmt ClassStmt) {
 	// create a scope to store `super`
 	if stmt.super != nil {
 		r.scopes = r.beginScope()
-		r.scopes.peek()["super"] = true
+		r.scopes.peek()["super"] = &variable{0, true}
 	}
 
 	// create a scope to store `this`
 	r.scopes = r.beginScope()
-	r.scopes.peek()["this"] = true
+	r.scopes.peek()["this"] = &variable{0, true}
 
 	for _, fun := range stmt.methods {
 		functionType := METHOD
@@ -206,13 +236,13 @@ func (r Resolver) declare(name Token) {
 	scope := r.scopes.peek()
 
 	if _, ok := scope[name.literal]; ok {
-		r.lox.errorReporter.error(ParseError{
+		r.lox.errorReporter.report(ResolveError{
 			name,
-			"a redeclared in this block",
+			name.literal + " redeclared in this block",
 		})
 	}
 
-	scope[name.literal] = false
+	scope[name.literal] = &variable{len(scope), false}
 }
 
 func (r Resolver) define(name Token) {
@@ -220,63 +250,66 @@ func (r Resolver) define(name Token) {
 	if len(r.scopes) == 0 {
 		return
 	}
-	r.scopes.peek()[name.literal] = true
+	r.scopes.peek()[name.literal].defined = true
 }
 
 func (r Resolver) visitAssignExpr(expr AssignExpr) interface{} {
 	r.resolveExpr(expr.right)
-	r.resolveLocal(expr, expr.left)
+	r.resolveLocal(expr.left)
 	return nil
 }
 
 func (r Resolver) visitThisExpr(expr ThisExpr) interface{} {
 	if r.currentClass == NONECLASS {
-		r.lox.errorReporter.error(ParseError{
+		r.lox.errorReporter.report(ResolveError{
 			expr.keyword,
 			"Illegal 'this'",
 		})
 	}
 
 	// resolve this like any other local variable.
-	r.resolveLocal(expr, expr.keyword)
+	r.resolveLocal(expr.keyword)
 	return nil
 }
 
 func (r Resolver) visitSuperExpr(expr SuperExpr) interface{} {
 	if r.currentClass != SUBCLASS {
-		r.lox.errorReporter.error(ParseError{
+		r.lox.errorReporter.report(ResolveError{
 			expr.keyword, "Illegal 'super'",
 		})
 	}
 
 	// give the distance about super to interpreter
-	r.resolveLocal(expr, expr.keyword)
+	r.resolveLocal(expr.keyword)
 	return nil
 }
 
 func (r Resolver) visitIdentifierExpr(expr IdentifierExpr) interface{} {
 	// lox Cannot read local variable in its own initializer.
 	if !r.scopes.isEmpty() {
-		if isDefined, ok := r.scopes.peek()[expr.name.literal]; ok && isDefined == false {
-			r.lox.errorReporter.error(ParseError{
+		if variable, ok := r.scopes.peek()[expr.name.literal]; ok && !variable.defined {
+			r.lox.errorReporter.report(ResolveError{
 				expr.name,
 				"Cannot read local variable in its own initializer.",
 			})
 		}
 	}
 
-	r.resolveLocal(expr, expr.name)
+	r.resolveLocal(expr.name)
 	return nil
 }
 
-func (r Resolver) resolveLocal(expr Expr, name Token) {
+func (r Resolver) resolveLocal(name Token) {
 	// When accessing a local variable
 	// We calculate the distance from the scope the var is accessed to the scope the var is declared.
 	// Then give the constant distance to interpreter
 	for i := len(r.scopes) - 1; i >= 0; i-- {
-		if _, ok := r.scopes[i][name.literal]; ok {
+		if variable, ok := r.scopes[i][name.literal]; ok {
 			distance := len(r.scopes) - i - 1
-			r.interpreter.resolve(expr, distance)
+			r.interpreter.resolve(name, distance, variable.slot)
+			if r.currentFunction == NONE {
+				r.lox.topLevel = append(r.lox.topLevel, name.offset)
+			}
 			return
 		}
 	}
@@ -305,9 +338,95 @@ func (r Resolver) visitIfStmt(stmt IfStmt) {
 
 func (r Resolver) visitWhileStmt(stmt WhileStmt) {
 	r.resolveExpr(stmt.condition)
+	if stmt.increment != nil {
+		r.resolveExpr(stmt.increment)
+	}
+
+	// r is a copy, so the loop is only known while resolving its body
+	r.loops = append(r.loops, stmt.label.literal)
 	r.resolveStmt(stmt.body)
 }
 
+func (r Resolver) visitForInStmt(stmt ForInStmt) {
+	r.resolveExpr(stmt.iterable)
+
+	// the loop variable lives in a scope of its own, which the interpreter recreates each iteration
+	r.scopes = r.beginScope()
+	r.declare(stmt.name)
+	r.define(stmt.name)
+	r.loops = append(r.loops, stmt.label.literal)
+	r.resolveStmt(stmt.body)
+	r.endScope()
+}
+
+func (r Resolver) visitThrowStmt(stmt ThrowStmt) {
+	r.resolveExpr(stmt.value)
+}
+
+func (r Resolver) visitTryStmt(stmt TryStmt) {
+	r.visitBlockStmt(stmt.body)
+
+	if stmt.catchBody != nil {
+		// the error variable and the catch block share a scope, like params and a function body
+		r.scopes = r.beginScope()
+		r.declare(stmt.name)
+		r.define(stmt.name)
+		r.resolveBody(stmt.catchBody.statements)
+		r.endScope()
+	}
+
+	if stmt.finallyBody != nil {
+		r.visitBlockStmt(*stmt.finallyBody)
+	}
+}
+
+func (r Resolver) visitImportStmt(stmt ImportStmt) {
+	r.declare(stmt.name)
+	r.define(stmt.name)
+}
+
+func (r Resolver) visitExportStmt(stmt ExportStmt) {
+	if len(r.scopes) > 0 || r.currentFunction != NONE {
+		r.lox.errorReporter.report(ResolveError{
+			stmt.keyword,
+			"Illegal export statement, it must be at the top level",
+		})
+	}
+	r.resolveStmt(stmt.declaration)
+}
+
+func (r Resolver) visitBreakStmt(stmt BreakStmt) {
+	r.checkLoopTarget(stmt.keyword, stmt.label)
+}
+
+func (r Resolver) visitContinueStmt(stmt ContinueStmt) {
+	r.checkLoopTarget(stmt.keyword, stmt.label)
+}
+
+// checkLoopTarget makes sure a break or continue has a loop to go to
+func (r Resolver) checkLoopTarget(keyword Token, label Token) {
+	if len(r.loops) == 0 {
+		r.lox.errorReporter.report(ResolveError{
+			keyword,
+			"Illegal " + keyword.literal + " statement, it must be inside a loop",
+		})
+		return
+	}
+
+	if label.literal == "" {
+		return
+	}
+	for _, loop := range r.loops {
+		if loop == label.literal {
+			return
+		}
+	}
+	r.lox.errorReporter.report(ResolveError{
+		label,
+		"Undefined label '" + label.literal + "'",
+	})
+}
+
 // func (r Resolver) visitPrintStmt(stmt PrintStmt) {
 // 	r.resolveExpr(stmt.expression)
 // }
@@ -315,7 +434,7 @@ func (r Resolver) visitWhileStmt(stmt WhileStmt) {
 func (r Resolver) visitReturnStmt(stmt ReturnStmt) {
 	// with `r.currentFunction`, we can know if we are in function body when we met a return statement
 	if r.currentFunction == NONE {
-		r.lox.errorReporter.error(ParseError{
+		r.lox.errorReporter.report(ResolveError{
 			stmt.keyword,
 			"Illegal return statement",
 		})
@@ -323,7 +442,7 @@ func (r Resolver) visitReturnStmt(stmt ReturnStmt) {
 
 	if stmt.value != nil {
 		if r.currentFunction == INITIALIZER {
-			r.lox.errorReporter.error(ParseError{
+			r.lox.errorReporter.report(ResolveError{
 				stmt.keyword,
 				"Illegal return statement, Cannot return a value form a initializer",
 			})
@@ -378,6 +497,41 @@ func (r Resolver) visitSequenceExpr(expr SequenceExpr) interface{} {
 	return nil
 }
 
+func (r Resolver) visitInterpolationExpr(expr InterpolationExpr) interface{} {
+	for _, part := range expr.parts {
+		r.resolveExpr(part)
+	}
+	return nil
+}
+
+func (r Resolver) visitListExpr(expr ListExpr) interface{} {
+	for _, element := range expr.elements {
+		r.resolveExpr(element)
+	}
+	return nil
+}
+
+func (r Resolver) visitMapExpr(expr MapExpr) interface{} {
+	for i, key := range expr.keys {
+		r.resolveExpr(key)
+		r.resolveExpr(expr.values[i])
+	}
+	return nil
+}
+
+func (r Resolver) visitIndexExpr(expr IndexExpr) interface{} {
+	r.resolveExpr(expr.object)
+	r.resolveExpr(expr.index)
+	return nil
+}
+
+func (r Resolver) visitIndexSetExpr(expr IndexSetExpr) interface{} {
+	r.resolveExpr(expr.value)
+	r.resolveExpr(expr.object)
+	r.resolveExpr(expr.index)
+	return nil
+}
+
 func (r Resolver) visitSetExpr(expr SetExpr) interface{} {
 	r.resolveExpr(expr.value)
 	r.resolveExpr(expr.object)
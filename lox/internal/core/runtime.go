@@ -0,0 +1,321 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// evalFilename names the source Eval runs in diagnostics
+const evalFilename = "<eval>"
+
+// Options configure a Runtime, see lox.Options
+type Options struct {
+	VM        bool
+	Stdout    io.Writer
+	Stderr    io.Writer
+	MaxSteps  int
+	MaxDepth  int
+	MaxMemory int
+	Allow     []string
+}
+
+// Runtime is what lox.Runtime runs programs with, its API is documented there
+type Runtime struct {
+	lox *Lox
+}
+
+// Error is a program that failed to compile, or an error nothing caught while it ran, see lox.Error
+type Error struct {
+	Code    string
+	Message string
+	File    string
+	Line    int
+	Column  int
+	Trace   []string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+func NewRuntime(options Options) (*Runtime, error) {
+	for _, capability := range options.Allow {
+		if err := checkCapability(capability); err != nil {
+			return nil, err
+		}
+	}
+	if options.MaxDepth == 0 {
+		options.MaxDepth = DefaultMaxDepth
+	}
+	l := &Lox{
+		errorReporter: &ErrorReporter{},
+		parser:        &Parser{},
+		scanner: &Scanner{
+			tokens: []Token{},
+		},
+		stdout:  options.Stdout,
+		stderr:  options.Stderr,
+		limits:  limits{maxSteps: options.MaxSteps, maxDepth: options.MaxDepth},
+		memory:  memory{max: options.MaxMemory, next: options.MaxMemory},
+		allowed: make(map[string]bool, len(options.Allow)),
+		lines:   make(map[string]int, 0),
+		modules: make(map[string]*Module, 0),
+	}
+	if l.stdout == nil {
+		l.stdout = os.Stdout
+	}
+	if l.stderr == nil {
+		l.stderr = ioutil.Discard
+	}
+	for _, capability := range options.Allow {
+		l.allowed[capability] = true
+	}
+	l.scanner.lox = l
+	l.errorReporter.lox = l
+	l.parser.lox = l
+	l.interpreter = NewInterpreter(l, newGlobals())
+	if options.VM {
+		l.vm = NewVM(l)
+	}
+	return &Runtime{l}, nil
+}
+
+func (r *Runtime) Eval(src string) (interface{}, error) {
+	return r.EvalContext(context.Background(), src)
+}
+
+func (r *Runtime) EvalContext(ctx context.Context, src string) (interface{}, error) {
+	l := r.lox
+	l.limits.start(ctx)
+	l.filename = evalFilename
+	// every Eval is numbered from line 1, and released once it ran
+	l.lines[evalFilename] = 0
+	eval := len(l.files)
+	defer l.release(eval)
+	stmts := l.compile(evalFilename, src)
+	if l.hasError {
+		return nil, l.takeError()
+	}
+
+	// the last expression statement is evaluated apart, for its value
+	var last Expr
+	if n := len(stmts); n > 0 {
+		if stmt, ok := stmts[n-1].(ExpressionStmt); ok {
+			last = stmt.expression
+			stmts = stmts[:n-1]
+		}
+	}
+
+	var value interface{}
+	if l.vm != nil {
+		function := l.compileEval(stmts, last)
+		if l.hasError {
+			return nil, l.takeError()
+		}
+		l.interpret(func() {
+			value = l.vm.interpret(function)
+		})
+	} else {
+		l.interpret(func() {
+			l.interpreter.executeBlock(stmts)
+			if last != nil {
+				value = l.interpreter.evaluate(last)
+			}
+		})
+	}
+
+	if err := l.takeError(); err != nil {
+		return nil, err
+	}
+	return ToGo(value), nil
+}
+
+func (r *Runtime) RunFile(path string) error {
+	return r.RunFileContext(context.Background(), path)
+}
+
+func (r *Runtime) RunFileContext(ctx context.Context, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	l := r.lox
+	l.limits.start(ctx)
+	l.filename = path
+
+	// the script is a module too, importing it back is reported as a cycle
+	if resolved, err := filepath.Abs(path); err == nil {
+		script := &Module{path: resolved, globals: l.interpreter.global, loading: true}
+		l.modules[resolved] = script
+		l.importing = append(l.importing, resolved)
+		defer func() {
+			script.loading = false
+			l.importing = l.importing[:len(l.importing)-1]
+		}()
+	}
+
+	l.run(string(content))
+	l.release(-1)
+	return l.takeError()
+}
+
+func (l *Lox) run(src string) {
+	if l.vm != nil {
+		function, _ := l.compileFile(l.filename, src)
+		if l.hasError {
+			return
+		}
+		l.interpret(func() {
+			l.vm.interpret(function)
+		})
+		return
+	}
+
+	stmts := l.compile(l.filename, src)
+	if l.hasError {
+		return
+	}
+	l.interpret(func() {
+		l.interpreter.executeBlock(stmts)
+	})
+}
+
+func (r *Runtime) SetGlobal(name string, value interface{}) error {
+	converted, err := toLox(value, name)
+	if err != nil {
+		return err
+	}
+	r.lox.interpreter.global.define(name, converted)
+	return nil
+}
+
+func (r *Runtime) SetNative(capability string, name string, fn interface{}) error {
+	if err := checkCapability(capability); err != nil {
+		return err
+	}
+	converted, err := toLox(fn, name)
+	if err != nil {
+		return err
+	}
+	native, ok := converted.(Callable)
+	if !ok {
+		return fmt.Errorf("lox: native %s is not a function", name)
+	}
+	r.lox.interpreter.defineNative(capability, name, native)
+	return nil
+}
+
+func (r *Runtime) GetGlobal(name string) (value interface{}, ok bool) {
+	value, ok = r.lox.interpreter.global.values[name]
+	return ToGo(value), ok
+}
+
+func (r *Runtime) Call(fn interface{}, args ...interface{}) (interface{}, error) {
+	return r.CallContext(context.Background(), fn, args...)
+}
+
+func (r *Runtime) CallContext(ctx context.Context, fn interface{}, args ...interface{}) (interface{}, error) {
+	l := r.lox
+	if name, ok := fn.(string); ok {
+		global, defined := l.interpreter.global.values[name]
+		if !defined {
+			return nil, fmt.Errorf("lox: undefined global '%s'", name)
+		}
+		fn = Ref{global}
+	}
+	callee, err := ToLox(fn)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if values[i], err = ToLox(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	l.hasError = false
+	l.hadRuntimeError = false
+	l.limits.start(ctx)
+	var result interface{}
+	l.interpret(func() {
+		result = l.interpreter.call(callee, hostToken, values)
+	})
+	l.release(-1)
+	if err := l.takeError(); err != nil {
+		return nil, err
+	}
+	return ToGo(result), nil
+}
+
+// hostToken stands for calls made by the host, they have no place in the source
+var hostToken = Token{tokentype: IDENTIFIER, literal: "<host>", offset: -1}
+
+func (r *Runtime) DumpAST(out io.Writer, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	l := r.lox
+	l.filename = path
+
+	stmts := l.compile(path, string(content))
+	if !l.hasError {
+		(&AstPrinter{out: out}).printStmts(stmts)
+	}
+	return l.takeError()
+}
+
+func (r *Runtime) Disassemble(out io.Writer, path string) error {
+	return r.lox.disassembleFile(out, path)
+}
+
+func (r *Runtime) REPL(in io.Reader) {
+	r.lox.runREPL(in)
+}
+
+// takeError renders the diagnostics collected so far to stderr, and returns the first error among them,
+// or the error of the limit that stopped the program
+func (l *Lox) takeError() error {
+	if stopped := l.stopped; stopped != nil {
+		l.stopped = nil
+		l.errorReporter.flush(l.stderr)
+		return stopped
+	}
+
+	var err *Error
+	for _, diagnostic := range l.errorReporter.diagnostics {
+		if diagnostic.severity == SeverityError {
+			err = l.newError(diagnostic)
+			break
+		}
+	}
+	l.errorReporter.flush(l.stderr)
+	if err == nil {
+		return nil
+	}
+	return err
+}
+
+func (l *Lox) newError(d Diagnostic) *Error {
+	err := &Error{
+		Code:    d.code,
+		Message: d.msg,
+		Line:    d.span.line,
+		Column:  d.span.column,
+	}
+	if d.span.offset >= 0 {
+		err.File = l.fileAt(d.span.offset).name
+	}
+	for _, entry := range d.trace {
+		err.Trace = append(err.Trace, entry.describe(l))
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDefaultDepthLimit(t *testing.T) {
+	for _, vm := range []bool{false, true} {
+		runtime, _ := NewRuntime(Options{VM: vm})
+		if _, err := runtime.Eval("fun f(n) { return 1 + f(n); } f(1);"); err != ErrDepthLimit {
+			t.Errorf("vm %v: runaway recursion ended with %v", vm, err)
+		}
+		if value, err := runtime.Eval("1 + 1;"); err != nil || value != 2.0 {
+			t.Errorf("vm %v: the runtime is broken after the limit stopped a program: %v, %v", vm, value, err)
+		}
+	}
+}
+
+func TestUnknownCapability(t *testing.T) {
+	if _, err := NewRuntime(Options{Allow: []string{CapabilityIO, "tiem"}}); err == nil {
+		t.Error("a runtime allowing 'tiem' was made")
+	}
+	runtime, err := NewRuntime(Options{Allow: []string{CapabilityTime}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.SetNative("bogus", "f", func() {}); err == nil {
+		t.Error("a native of capability 'bogus' was defined")
+	}
+}
+
+// memoryHogs keep what natives, blocks and property reads make alive until the memory limit stops them
+var memoryHogs = map[string]string{
+	"slice":  `keep.push(xs.slice(0, 2000));`,
+	"map":    `keep.push(xs.map(fun (x) { return x; }));`,
+	"filter": `keep.push(xs.filter(fun (x) { return true; }));`,
+	"keys":   `keep.push(m.keys()); keep.push(m.values());`,
+	"go":     `keep.push(words(50));`,
+	"blocks": `{ var x = 1; keep.push(fun () { return x; }); }`,
+}
+
+func TestMemoryLimitCountsWhatNativesMake(t *testing.T) {
+	const limit = 200000
+	for name, hog := range memoryHogs {
+		for _, vm := range []bool{false, true} {
+			runtime, _ := NewRuntime(Options{VM: vm, MaxMemory: limit})
+			runtime.SetGlobal("words", func(n int) []string { return make([]string, n) })
+			_, err := runtime.Eval(`
+				var xs = [];
+				for (var i = 0; i < 2000; i = i + 1) xs.push(i);
+				var m = {};
+				for (var i = 0; i < 500; i = i + 1) m[i] = i;
+				var keep = [];
+				while (true) ` + hog)
+			lerr, ok := err.(*Error)
+			if !ok || !strings.HasPrefix(lerr.Message, "Out of memory") {
+				t.Errorf("%s, vm %v: stopped with %v", name, vm, err)
+				continue
+			}
+			var held int
+			fmt.Sscanf(lerr.Message, "Out of memory: the program holds %d", &held)
+			if held > 2*limit {
+				t.Errorf("%s, vm %v: the program held %d bytes before it was stopped", name, vm, held)
+			}
+		}
+	}
+}
+
+func TestMemoryIsNotMeasuredOnEveryAllocationNearTheLimit(t *testing.T) {
+	const limit = 200000
+	for _, vm := range []bool{false, true} {
+		runtime, _ := NewRuntime(Options{VM: vm, MaxMemory: limit})
+		runtime.SetGlobal("keep", strings.Repeat("x", limit*97/100))
+		_, err := runtime.Eval(`for (var i = 0; i < 20000; i = i + 1) { var garbage = [i, i, i]; }`)
+		if err != nil {
+			t.Fatalf("vm %v: a program under its limit failed: %v", vm, err)
+		}
+		if measured := runtime.lox.memory.measured; measured > 200 {
+			t.Errorf("vm %v: a program holding close to its limit was measured %d times", vm, measured)
+		}
+	}
+}
+
+func TestEvalLinesAndRelease(t *testing.T) {
+	for _, vm := range []bool{false, true} {
+		runtime, _ := NewRuntime(Options{VM: vm})
+		l := runtime.lox
+		runtime.Eval("var a = 1;\nfun add(x) { { var y = x; return y + a; } }")
+		if _, err := runtime.Eval("1 + nil;"); err == nil || err.(*Error).Line != 1 {
+			t.Errorf("vm %v: an error on the first line of an Eval is reported as %v", vm, err)
+		}
+
+		files, locals := len(l.files), len(l.interpreter.locals)
+		for i := 0; i < 1000; i++ {
+			if value, err := runtime.Eval("{ var b = add(2);\n b; }\nadd(1);"); err != nil || value != 2.0 {
+				t.Fatalf("vm %v: a function from an earlier Eval returned %v, %v", vm, value, err)
+			}
+		}
+		if len(l.files) != files || len(l.interpreter.locals) != locals {
+			t.Errorf("vm %v: 1000 Evals grew the files from %d to %d and the locals from %d to %d",
+				vm, files, len(l.files), locals, len(l.interpreter.locals))
+		}
+		for _, file := range l.files {
+			if file.src != "" {
+				t.Errorf("vm %v: the source of an Eval is kept: %q", vm, file.src)
+			}
+		}
+		if _, err := runtime.Eval("\nadd(nil);"); err == nil || err.(*Error).Line != 2 {
+			t.Errorf("vm %v: an error in a function from an earlier Eval is reported as %v", vm, err)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package core
 
 type Stmt interface {
 	accept(visitor StmtVisitor)
@@ -24,6 +24,8 @@ type VarStmt struct {
 	name Token
 
 	init Expr
+
+	doc string
 }
 
 func (s VarStmt) accept(visitor StmtVisitor) {
@@ -38,6 +40,8 @@ type ClassStmt struct {
 	methods []FunStmt
 
 	staticMethods []FunStmt
+
+	doc string
 }
 
 func (s ClassStmt) accept(visitor StmtVisitor) {
@@ -60,6 +64,8 @@ type FunStmt struct {
 	params []Token
 
 	body BlockStmt
+
+	doc string
 }
 
 func (s FunStmt) accept(visitor StmtVisitor) {
@@ -82,8 +88,94 @@ type WhileStmt struct {
 	condition Expr
 
 	body Stmt
+
+	increment Expr
+
+	label Token
 }
 
 func (s WhileStmt) accept(visitor StmtVisitor) {
 	visitor.visitWhileStmt(s)
 }
+
+type ForInStmt struct {
+	name Token
+
+	keyword Token
+
+	iterable Expr
+
+	body Stmt
+
+	label Token
+}
+
+func (s ForInStmt) accept(visitor StmtVisitor) {
+	visitor.visitForInStmt(s)
+}
+
+type ThrowStmt struct {
+	keyword Token
+
+	value Expr
+}
+
+func (s ThrowStmt) accept(visitor StmtVisitor) {
+	visitor.visitThrowStmt(s)
+}
+
+type TryStmt struct {
+	body BlockStmt
+
+	name Token
+
+	catchBody *BlockStmt
+
+	finallyBody *BlockStmt
+}
+
+func (s TryStmt) accept(visitor StmtVisitor) {
+	visitor.visitTryStmt(s)
+}
+
+type ImportStmt struct {
+	keyword Token
+
+	path Token
+
+	name Token
+}
+
+func (s ImportStmt) accept(visitor StmtVisitor) {
+	visitor.visitImportStmt(s)
+}
+
+type ExportStmt struct {
+	keyword Token
+
+	declaration Stmt
+}
+
+func (s ExportStmt) accept(visitor StmtVisitor) {
+	visitor.visitExportStmt(s)
+}
+
+type BreakStmt struct {
+	keyword Token
+
+	label Token
+}
+
+func (s BreakStmt) accept(visitor StmtVisitor) {
+	visitor.visitBreakStmt(s)
+}
+
+type ContinueStmt struct {
+	keyword Token
+
+	label Token
+}
+
+func (s ContinueStmt) accept(visitor StmtVisitor) {
+	visitor.visitContinueStmt(s)
+}
@@ -0,0 +1,401 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Token is A struct contains lexeme or token info
+type Token struct {
+	tokentype TokenType
+	lexeme    interface{}
+	literal   string
+	line      int
+	column    int
+	// offset and length locate the lexeme among everything compiled, see Lox.fileAt, in bytes
+	offset int
+	length int
+	// doc holds the `///` doc comments right before the token, one line each
+	doc string
+}
+
+// TokenError implement the std err interface
+type TokenError struct {
+	code   string
+	msg    string
+	line   int
+	column int
+	offset int
+	length int
+}
+
+func (e TokenError) Error() string {
+	// TODO: more elgant error display
+	return fmt.Sprintf("[GLOX] TokenError: Line %d, Cloumn %d, %s", e.line, e.column, e.msg)
+}
+
+// Scanner is Lexeme anglizer, it walks the source rune by rune following the lox lexical grammar
+type Scanner struct {
+	lox    *Lox
+	tokens []Token
+	source string
+	// start and current are byte offsets of the token being scanned in source
+	start   int
+	current int
+	// line and column are where current is, startLine and startColumn where start is
+	line        int
+	column      int
+	startLine   int
+	startColumn int
+	// lineOffset and offset are added to every token position,
+	// they are non-zero when source is not the first input Lox has seen
+	lineOffset int
+	offset     int
+	// docs collects `///` comment lines until the next token takes them
+	docs []string
+	// interpolations counts the '{' left open inside each unfinished `${`,
+	// the '}' matching the `${` resumes scanning the string
+	interpolations []int
+}
+
+func (t *Scanner) scanTokens() {
+	t.start, t.current = 0, 0
+	t.line, t.column = 1, 1
+
+	for !t.isAtEnd() {
+		t.start = t.current
+		t.startLine, t.startColumn = t.line, t.column
+		t.scanToken()
+	}
+
+	if len(t.interpolations) > 0 {
+		t.lox.errorReporter.report(t.error("Unterminated string interpolation"))
+		t.interpolations = t.interpolations[:0]
+	}
+
+	t.start = t.current
+	t.startLine, t.startColumn = t.line, t.column
+	t.addToken(EOF, nil)
+}
+
+func (t *Scanner) scanToken() {
+	c := t.advance()
+	switch c {
+	case '(':
+		t.addToken(LEFT_PAREN, "(")
+	case ')':
+		t.addToken(RIGHT_PAREN, ")")
+	case '{':
+		if depth := len(t.interpolations); depth > 0 {
+			t.interpolations[depth-1]++
+		}
+		t.addToken(LEFT_BRACE, "{")
+	case '}':
+		if depth := len(t.interpolations); depth > 0 {
+			if t.interpolations[depth-1] == 0 {
+				// back into the string the interpolation is part of
+				t.interpolations = t.interpolations[:depth-1]
+				t.string()
+				return
+			}
+			t.interpolations[depth-1]--
+		}
+		t.addToken(RIGHT_BRACE, "}")
+	case '[':
+		t.addToken(LEFT_BRACKET, "[")
+	case ']':
+		t.addToken(RIGHT_BRACKET, "]")
+	case ',':
+		t.addToken(COMMA, ",")
+	case '.':
+		t.addToken(DOT, ".")
+	case '-':
+		t.addToken(MINUS, "-")
+	case '+':
+		t.addToken(PLUS, "+")
+	case ';':
+		t.addToken(SEMICOLON, ";")
+	case '*':
+		t.addToken(STAR, "*")
+	case '?':
+		t.addToken(QUESTION, "?")
+	case ':':
+		t.addToken(COLON, ":")
+	case '!':
+		t.addOperator('=', BANG_EQUAL, BANG)
+	case '=':
+		t.addOperator('=', EQUAL_EQUAL, EQUAL)
+	case '<':
+		t.addOperator('=', LESS_EQUAL, LESS)
+	case '>':
+		t.addOperator('=', GREATER_EQUAL, GREATER)
+	case '/':
+		if t.match('/') {
+			t.lineComment()
+		} else if t.match('*') {
+			t.blockComment()
+		} else {
+			t.addToken(SLASH, "/")
+		}
+	case ' ', '\r', '\t', '\n':
+		// skip whitespace, advance already keeps track of lines
+	case '"':
+		t.string()
+	default:
+		if isDigit(c) {
+			t.number()
+		} else if isAlpha(c) {
+			t.identifier()
+		} else {
+			t.lox.errorReporter.report(t.error("Invalid or unexpected token: " + t.text()))
+		}
+	}
+}
+
+// addOperator adds the two-character operator if the next rune is second, or the single one otherwise
+func (t *Scanner) addOperator(second rune, double TokenType, single TokenType) {
+	if t.match(second) {
+		t.addToken(double, t.text())
+	} else {
+		t.addToken(single, t.text())
+	}
+}
+
+// lineComment goes until the end of the line,
+// `///` makes it a doc comment for the declaration that follows
+func (t *Scanner) lineComment() {
+	isDoc := t.peek() == '/' && t.peekNext() != '/'
+	for t.peek() != '\n' && !t.isAtEnd() {
+		t.advance()
+	}
+
+	if isDoc {
+		doc := t.text()[len("///"):]
+		if len(doc) > 0 && doc[0] == ' ' {
+			doc = doc[1:]
+		}
+		t.docs = append(t.docs, doc)
+	}
+}
+
+// blockComment skips `/* ... */`, comments nest, so `/* /* */ */` is a single comment
+func (t *Scanner) blockComment() {
+	depth := 1
+	for !t.isAtEnd() {
+		if t.match('/') {
+			if t.match('*') {
+				depth++
+			}
+		} else if t.match('*') {
+			if t.match('/') {
+				depth--
+				if depth == 0 {
+					return
+				}
+			}
+		} else {
+			t.advance()
+		}
+	}
+	t.lox.errorReporter.report(t.error("Unterminated comment"))
+}
+
+// string scans a string literal, or the part of it up to the next `${`.
+// An interpolated expression is scanned as normal tokens,
+// then the '}' closing it brings us back here to scan the rest of the string.
+func (t *Scanner) string() {
+	var value strings.Builder
+
+	// lox strings may span multiple lines
+	for t.peek() != '"' && !t.isAtEnd() {
+		c := t.advance()
+		if c == '\\' {
+			t.escape(&value)
+		} else if c == '$' && t.peek() == '{' {
+			t.advance()
+			t.addToken(INTERPOLATION, value.String())
+			t.interpolations = append(t.interpolations, 0)
+			return
+		} else {
+			value.WriteRune(c)
+		}
+	}
+
+	if t.isAtEnd() {
+		t.lox.errorReporter.report(t.error("Unterminated string"))
+		return
+	}
+
+	// the closing "
+	t.advance()
+	t.addToken(STRING, value.String())
+}
+
+// escape writes the character escaped by the sequence after a backslash
+func (t *Scanner) escape(value *strings.Builder) {
+	if t.isAtEnd() {
+		return
+	}
+
+	// errors point at the escape sequence, not at the whole string
+	line, column, start := t.line, t.column-1, t.current-1
+	switch c := t.advance(); c {
+	case 'n':
+		value.WriteRune('\n')
+	case 't':
+		value.WriteRune('\t')
+	case 'r':
+		value.WriteRune('\r')
+	case '0':
+		value.WriteRune(0)
+	case '"', '\\', '$':
+		value.WriteRune(c)
+	case 'u':
+		// \u{...} holds the hexadecimal code point
+		if !t.match('{') {
+			t.lox.errorReporter.report(t.errorFrom(start, line, column, "Expect '{' after \\u"))
+			return
+		}
+		digits := t.current
+		for t.peek() != '}' && t.peek() != '"' && !t.isAtEnd() {
+			t.advance()
+		}
+		code, err := strconv.ParseUint(t.source[digits:t.current], 16, 32)
+		if !t.match('}') || err != nil || !utf8.ValidRune(rune(code)) {
+			t.lox.errorReporter.report(t.errorFrom(start, line, column, "Invalid unicode escape sequence"))
+			return
+		}
+		value.WriteRune(rune(code))
+	default:
+		t.lox.errorReporter.report(t.errorFrom(start, line, column, "Invalid escape sequence: \\"+string(c)))
+	}
+}
+
+func (t *Scanner) number() {
+	for isDigit(t.peek()) {
+		t.advance()
+	}
+
+	// look for a fractional part, a trailing '.' is left for method calls like `1.foo`
+	if t.peek() == '.' && isDigit(t.peekNext()) {
+		t.advance()
+		for isDigit(t.peek()) {
+			t.advance()
+		}
+	}
+
+	// turn all numeric thing to float64, like javascript
+	num, err := strconv.ParseFloat(t.text(), 64)
+	if err != nil {
+		t.lox.errorReporter.report(t.error("Unexpected number"))
+		return
+	}
+	t.addToken(NUMBER, num)
+}
+
+func (t *Scanner) identifier() {
+	// only begin with `_`, alpha and contains only alpha-numeric charactors
+	for isAlpha(t.peek()) || isDigit(t.peek()) {
+		t.advance()
+	}
+
+	text := t.text()
+	if tokenType, ok := keywords[text]; ok {
+		t.addToken(tokenType, text)
+	} else {
+		t.addToken(IDENTIFIER, text)
+	}
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func (t *Scanner) isAtEnd() bool {
+	return t.current >= len(t.source)
+}
+
+// advance consumes a rune and returns it
+func (t *Scanner) advance() rune {
+	c, size := utf8.DecodeRuneInString(t.source[t.current:])
+	t.current += size
+	if c == '\n' {
+		t.line++
+		t.column = 1
+	} else {
+		t.column++
+	}
+	return c
+}
+
+// peek returns the rune we have yet to consume, without consuming it
+func (t *Scanner) peek() rune {
+	if t.isAtEnd() {
+		return 0
+	}
+	c, _ := utf8.DecodeRuneInString(t.source[t.current:])
+	return c
+}
+
+// peekNext returns the rune after peek
+func (t *Scanner) peekNext() rune {
+	if t.isAtEnd() {
+		return 0
+	}
+	_, size := utf8.DecodeRuneInString(t.source[t.current:])
+	if t.current+size >= len(t.source) {
+		return 0
+	}
+	c, _ := utf8.DecodeRuneInString(t.source[t.current+size:])
+	return c
+}
+
+// match consumes the next rune only if it is the expected one
+func (t *Scanner) match(expected rune) bool {
+	if t.peek() != expected || t.isAtEnd() {
+		return false
+	}
+	t.advance()
+	return true
+}
+
+// text is the source of the token being scanned
+func (t *Scanner) text() string {
+	return t.source[t.start:t.current]
+}
+
+func (t *Scanner) addToken(tokentype TokenType, value interface{}) {
+	t.tokens = append(t.tokens, Token{
+		tokentype: tokentype,
+		lexeme:    value,
+		literal:   t.text(),
+		line:      t.startLine + t.lineOffset,
+		column:    t.startColumn,
+		offset:    t.start + t.offset,
+		length:    t.current - t.start,
+		doc:       strings.Join(t.docs, "\n"),
+	})
+	t.docs = t.docs[:0]
+}
+
+// error builds a TokenError spanning the token being scanned
+func (t *Scanner) error(msg string) TokenError {
+	return t.errorFrom(t.start, t.startLine, t.startColumn, msg)
+}
+
+// errorFrom builds a TokenError spanning from start, at line and column, up to the current rune
+func (t *Scanner) errorFrom(start int, line int, column int, msg string) TokenError {
+	return TokenError{
+		msg:    msg,
+		line:   line + t.lineOffset,
+		column: column,
+		offset: start + t.offset,
+		length: t.current - start,
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package core
 
 type TokenType int
 
@@ -8,6 +8,8 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
 	DOT
 	MINUS
@@ -35,17 +37,27 @@ const (
 	// Literals.
 	IDENTIFIER
 	STRING
+	// INTERPOLATION is a string part followed by an interpolated `${expression}`
+	INTERPOLATION
 	NUMBER
 
 	// Keywords.
 	AND
+	AS
+	BREAK
+	CATCH
 	CLASS
+	CONTINUE
 	STATIC
 	ELSE
+	EXPORT
 	FALSE
+	FINALLY
 	FUN
 	FOR
 	IF
+	IMPORT
+	IN
 	NIL
 	OR
 
@@ -54,7 +66,9 @@ const (
 	RETURN
 	SUPER
 	THIS
+	THROW
 	TRUE
+	TRY
 	VAR
 	WHILE
 
@@ -62,20 +76,30 @@ const (
 )
 
 var keywords = map[string]TokenType{
-	"and":   AND,
-	"class": CLASS,
-	"else":  ELSE,
-	"false": FALSE,
-	"for":   FOR,
-	"fun":   FUN,
-	"if":    IF,
-	"nil":   NIL,
-	"or":    OR,
+	"and":      AND,
+	"as":       AS,
+	"break":    BREAK,
+	"class":    CLASS,
+	"continue": CONTINUE,
+	"catch":    CATCH,
+	"else":     ELSE,
+	"export":   EXPORT,
+	"false":    FALSE,
+	"finally":  FINALLY,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"import":   IMPORT,
+	"in":       IN,
+	"nil":      NIL,
+	"or":       OR,
 	// "print":  PRINT,
 	"return": RETURN,
 	"super":  SUPER,
 	"this":   THIS,
+	"throw":  THROW,
 	"true":   TRUE,
+	"try":    TRY,
 	"var":    VAR,
 	"while":  WHILE,
 	"static": STATIC,
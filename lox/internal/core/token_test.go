@@ -0,0 +1,114 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+// scan scans src on its own, and returns its tokens and what was reported
+func scan(src string) ([]Token, []Diagnostic) {
+	runtime, _ := NewRuntime(Options{})
+	l := runtime.lox
+	l.scanner.source = src
+	l.scanner.scanTokens()
+	return l.scanner.tokens, l.errorReporter.diagnostics
+}
+
+func TestScanner(t *testing.T) {
+	tokens, diagnostics := scan("var a = 1.5;\n  print(\"é\" >= 10) // comment\n1.len != nil")
+	if len(diagnostics) > 0 {
+		t.Fatalf("reported %v", diagnostics)
+	}
+	want := []struct {
+		tokentype TokenType
+		lexeme    interface{}
+		line      int
+		column    int
+		offset    int
+	}{
+		{VAR, "var", 1, 1, 0},
+		{IDENTIFIER, "a", 1, 5, 4},
+		{EQUAL, "=", 1, 7, 6},
+		{NUMBER, 1.5, 1, 9, 8},
+		{SEMICOLON, ";", 1, 12, 11},
+		{IDENTIFIER, "print", 2, 3, 15},
+		{LEFT_PAREN, "(", 2, 8, 20},
+		{STRING, "é", 2, 9, 21},
+		// columns count runes, offsets count bytes
+		{GREATER_EQUAL, ">=", 2, 13, 26},
+		{NUMBER, 10.0, 2, 16, 29},
+		{RIGHT_PAREN, ")", 2, 18, 31},
+		// a trailing '.' is left for the method call
+		{NUMBER, 1.0, 3, 1, 44},
+		{DOT, ".", 3, 2, 45},
+		{IDENTIFIER, "len", 3, 3, 46},
+		{BANG_EQUAL, "!=", 3, 7, 50},
+		{NIL, "nil", 3, 10, 53},
+		{EOF, nil, 3, 13, 56},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("scanned %d tokens: %v", len(tokens), tokens)
+	}
+	for i, token := range tokens {
+		w := want[i]
+		if token.tokentype != w.tokentype || !reflect.DeepEqual(token.lexeme, w.lexeme) ||
+			token.line != w.line || token.column != w.column || token.offset != w.offset {
+			t.Errorf("token %d is %v %#v at %d:%d, offset %d, want %v %#v at %d:%d, offset %d",
+				i, token.tokentype, token.lexeme, token.line, token.column, token.offset,
+				w.tokentype, w.lexeme, w.line, w.column, w.offset)
+		}
+	}
+}
+
+func TestScannerErrors(t *testing.T) {
+	tests := map[string]string{
+		"1 @ 2": "Invalid or unexpected token: @",
+		"\"abc": "Unterminated string",
+	}
+	for src, msg := range tests {
+		_, diagnostics := scan(src)
+		if len(diagnostics) != 1 || diagnostics[0].code != CodeLexical || diagnostics[0].msg != msg {
+			t.Errorf("scanning %q reported %v, want %s", src, diagnostics, msg)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tokens, diagnostics := scan(`"tab\there\n\"q\" \\ \$ \u{1F600}\0"`)
+	if len(diagnostics) > 0 {
+		t.Fatalf("reported %v", diagnostics)
+	}
+	if want := "tab\there\n\"q\" \\ $ \U0001F600\x00"; tokens[0].lexeme != want {
+		t.Errorf("the string is %q, want %q", tokens[0].lexeme, want)
+	}
+
+	// the error points at the escape sequence
+	_, diagnostics = scan(`"ok" "bad \q" "\u{110000}"`)
+	if len(diagnostics) != 2 || diagnostics[0].msg != "Invalid escape sequence: \\q" || diagnostics[0].span.column != 11 ||
+		diagnostics[1].msg != "Invalid unicode escape sequence" || diagnostics[1].span.column != 16 {
+		t.Errorf("reported %v", diagnostics)
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	tests := map[string]string{
+		`"a ${1 + 2} b";`:                   "a 3 b",
+		`"${"x"}${"y"}";`:                   "xy",
+		`"m ${ {"k": [1, 2]}["k"][1] } n";`: "m 2 n",
+		`"outer ${"inner ${1 + 1}"}";`:      "outer inner 2",
+		`"not \${interpolated}";`:           "not ${interpolated}",
+		`var n = 1.5; "n is ${n > 1}";`:     "n is true",
+	}
+	for src, want := range tests {
+		for _, vm := range []bool{false, true} {
+			runtime, _ := NewRuntime(Options{VM: vm})
+			if value, err := runtime.Eval(src); err != nil || value != want {
+				t.Errorf("vm %v: %s is %q, %v, want %q", vm, src, value, err, want)
+			}
+		}
+	}
+
+	if _, diagnostics := scan(`"open ${1 + `); len(diagnostics) == 0 {
+		t.Error("an unterminated interpolation was not reported")
+	}
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Ref is a lox value go has no counterpart for, see lox.Ref
+type Ref struct {
+	value interface{}
+}
+
+// String is how print shows the value
+func (r Ref) String() string {
+	return stringify(r.value)
+}
+
+// ToGo converts a lox value for go, see lox.ToGo
+func ToGo(value interface{}) interface{} {
+	return toGo(value, make(map[interface{}]interface{}, 0))
+}
+
+// toGo converts value, seen holds the lists and maps converted so far
+func toGo(value interface{}, seen map[interface{}]interface{}) interface{} {
+	switch value := value.(type) {
+	case nil, bool, float64, string:
+		return value
+	case *List:
+		if converted, ok := seen[value]; ok {
+			return converted
+		}
+		elements := make([]interface{}, len(value.elements))
+		seen[value] = elements
+		for i, element := range value.elements {
+			elements[i] = toGo(element, seen)
+		}
+		return elements
+	case *Map:
+		if converted, ok := seen[value]; ok {
+			return converted
+		}
+		entries := make(map[interface{}]interface{}, len(value.order))
+		seen[value] = entries
+		for _, key := range value.order {
+			entries[key] = toGo(value.entries[key], seen)
+		}
+		return entries
+	case *goObject:
+		return value.value.Interface()
+	}
+	return Ref{value}
+}
+
+// ToLox converts a go value for lox, see lox.ToLox
+func ToLox(value interface{}) (interface{}, error) {
+	return toLox(value, "")
+}
+
+// toLox converts value, name is what a func is called in lox, the name go gave it when empty
+func toLox(value interface{}, name string) (interface{}, error) {
+	switch value := value.(type) {
+	case nil, bool, float64, string:
+		return value, nil
+	case Ref:
+		return value.value, nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Slice, reflect.Array:
+		elements := make([]interface{}, v.Len())
+		for i := range elements {
+			element, err := toLox(v.Index(i).Interface(), "")
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return NewList(elements), nil
+	case reflect.Map:
+		keys := make([]interface{}, 0, v.Len())
+		values := make(map[interface{}]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := toLox(iter.Key().Interface(), "")
+			if err != nil {
+				return nil, err
+			}
+			switch key := key.(type) {
+			case float64:
+				if math.IsNaN(key) {
+					return nil, fmt.Errorf("lox: cannot use NaN as a map key")
+				}
+			case nil, bool, string:
+			default:
+				return nil, fmt.Errorf("lox: cannot use %T as a map key", iter.Key().Interface())
+			}
+			if values[key], err = toLox(iter.Value().Interface(), ""); err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keyLess(keys[i], keys[j])
+		})
+		m := NewMap()
+		for _, key := range keys {
+			m.put(key, values[key])
+		}
+		return m, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if name == "" {
+			name = funcName(v)
+		}
+		return goFunction(name, v)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			return &goObject{v}, nil
+		}
+	case reflect.Struct:
+		copied := reflect.New(v.Type())
+		copied.Elem().Set(v)
+		return &goObject{copied}, nil
+	}
+	return nil, fmt.Errorf("lox: cannot convert %T to a lox value", value)
+}
+
+// keyLess orders map keys, nil first, then booleans, numbers and strings
+func keyLess(a, b interface{}) bool {
+	rank := func(key interface{}) int {
+		switch key.(type) {
+		case nil:
+			return 0
+		case bool:
+			return 1
+		case float64:
+			return 2
+		}
+		return 3
+	}
+	if rank(a) != rank(b) {
+		return rank(a) < rank(b)
+	}
+	switch a := a.(type) {
+	case bool:
+		return !a && b.(bool)
+	case float64:
+		return a < b.(float64)
+	case string:
+		return a < b.(string)
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package core
+
+import "errors"
+
+var errInvalidBytecode = errors.New("invalid bytecode")
+
+// verify makes sure the VM can run function, and disasm list it, without reading past its code, its constants or its stack.
+// Bytecode read from a cache may be anything, it is only used once it verifies.
+//
+// Every instruction has its operands checked against the code and the constant table first.
+// Then every instruction reachable from the start is visited with the height of the stack there,
+// counted from the callee slot, so local slots and the values instructions pop are checked against it.
+// Jumps must land on an instruction, with the same height however it is reached.
+func verify(function *FunctionProto) error {
+	chunk := &function.chunk
+	code := chunk.code
+	if function.arity > 0xff || function.upvalueCount > 0x100 || len(chunk.refs) != len(code) {
+		return errInvalidBytecode
+	}
+	for _, constant := range chunk.constants {
+		if nested, ok := constant.(*FunctionProto); ok {
+			if err := verify(nested); err != nil {
+				return err
+			}
+		}
+	}
+
+	// starts marks the offsets instructions start at
+	starts := make([]bool, len(code))
+	for offset := 0; offset < len(code); {
+		next, ok := checkOperands(function, offset)
+		if !ok {
+			return errInvalidBytecode
+		}
+		starts[offset] = true
+		offset = next
+	}
+
+	// heights holds the stack height at the start of each instruction, -1 until it is reached
+	heights := make([]int, len(code))
+	for i := range heights {
+		heights[i] = -1
+	}
+	pending := make([]int, 0)
+	reach := func(offset int, height int) bool {
+		if offset < 0 || offset >= len(code) || !starts[offset] {
+			return false
+		}
+		if heights[offset] < 0 {
+			heights[offset] = height
+			pending = append(pending, offset)
+		}
+		return heights[offset] == height
+	}
+	// the callee and its arguments are there when the function starts
+	if !reach(0, function.arity+1) {
+		return errInvalidBytecode
+	}
+
+	for len(pending) > 0 {
+		offset := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		height := heights[offset]
+		op := OpCode(code[offset])
+		operand := readOperand(op, code, offset)
+		next, _ := checkOperands(function, offset)
+
+		// pops is how many values the instruction takes or peeks at, pushes how many it leaves in their place
+		pops, pushes := 0, 0
+		switch op {
+		case OP_CONSTANT, OP_NIL, OP_TRUE, OP_FALSE, OP_GET_GLOBAL, OP_GET_UPVALUE, OP_CLASS, OP_IMPORT, OP_CLOSURE:
+			pushes = 1
+		case OP_POP, OP_CLOSE_UPVALUE, OP_DEFINE_GLOBAL:
+			pops = 1
+		case OP_GET_LOCAL, OP_SET_LOCAL:
+			if operand >= height {
+				return errInvalidBytecode
+			}
+			if op == OP_GET_LOCAL {
+				pushes = 1
+			} else {
+				pops, pushes = 1, 1
+			}
+		case OP_SET_GLOBAL, OP_SET_UPVALUE, OP_GET_PROPERTY, OP_NOT, OP_NEGATE, OP_ITERATOR:
+			pops, pushes = 1, 1
+		case OP_SET_PROPERTY, OP_GET_SUPER, OP_GET_INDEX, OP_INHERIT, OP_METHOD, OP_STATIC_METHOD,
+			OP_EQUAL, OP_NOT_EQUAL, OP_GREATER, OP_GREATER_EQUAL, OP_LESS, OP_LESS_EQUAL,
+			OP_ADD, OP_SUBTRACT, OP_MULTIPLY, OP_DIVIDE:
+			pops, pushes = 2, 1
+		case OP_SET_INDEX:
+			pops, pushes = 3, 1
+		case OP_CALL, OP_TAIL_CALL:
+			pops, pushes = operand+1, 1
+		case OP_LIST, OP_INTERPOLATE:
+			pops, pushes = operand, 1
+		case OP_MAP:
+			pops, pushes = 2*operand, 1
+		case OP_JUMP, OP_LOOP:
+			if !reach(jumpTarget(op, next, operand), height) {
+				return errInvalidBytecode
+			}
+			continue
+		case OP_JUMP_IF_FALSE, OP_FOR_NEXT:
+			// the condition or the iterator stays on the stack, the next value goes on top of the iterator
+			if height < 2 || !reach(jumpTarget(op, next, operand), height) {
+				return errInvalidBytecode
+			}
+			if op == OP_FOR_NEXT {
+				pushes = 1
+			}
+		case OP_TRY, OP_TRY_FINALLY:
+			// the handler starts with the caught value pushed
+			if !reach(jumpTarget(op, next, operand), height+1) {
+				return errInvalidBytecode
+			}
+		case OP_POP_HANDLER:
+		case OP_RETURN, OP_THROW, OP_RETHROW:
+			// nothing after them runs
+			if height < 2 {
+				return errInvalidBytecode
+			}
+			continue
+		}
+
+		if op == OP_CLOSURE {
+			// a local function is pushed before capturing, it captures its own slot
+			nested := chunk.constants[operand].(*FunctionProto)
+			for i := 0; i < nested.upvalueCount; i++ {
+				if code[offset+3+2*i] == 1 && int(code[offset+4+2*i]) > height {
+					return errInvalidBytecode
+				}
+			}
+		}
+
+		// the callee slot of the frame is never popped
+		if height-pops < 1 || !reach(next, height-pops+pushes) {
+			return errInvalidBytecode
+		}
+	}
+	return nil
+}
+
+// checkOperands checks the operands of the instruction at offset, and returns where the next one starts
+func checkOperands(function *FunctionProto, offset int) (next int, ok bool) {
+	chunk := &function.chunk
+	code := chunk.code
+	op := OpCode(code[offset])
+	size := op.size()
+	if size == 0 || offset+size > len(code) {
+		return 0, false
+	}
+	operand := readOperand(op, code, offset)
+	next = offset + size
+
+	switch op {
+	case OP_CONSTANT, OP_GET_PROPERTY, OP_SET_PROPERTY, OP_IMPORT:
+		return next, operand < len(chunk.constants)
+	case OP_GET_GLOBAL, OP_DEFINE_GLOBAL, OP_SET_GLOBAL, OP_GET_SUPER, OP_CLASS, OP_METHOD, OP_STATIC_METHOD:
+		// these are named by a string constant
+		if operand >= len(chunk.constants) {
+			return 0, false
+		}
+		_, ok = chunk.constants[operand].(string)
+		return next, ok
+	case OP_GET_UPVALUE, OP_SET_UPVALUE:
+		return next, operand < function.upvalueCount
+	case OP_CLOSURE:
+		if operand >= len(chunk.constants) {
+			return 0, false
+		}
+		nested, ok := chunk.constants[operand].(*FunctionProto)
+		if !ok || next+2*nested.upvalueCount > len(code) {
+			return 0, false
+		}
+		for i := 0; i < nested.upvalueCount; i++ {
+			isLocal, index := code[next], int(code[next+1])
+			if isLocal > 1 || isLocal == 0 && index >= function.upvalueCount {
+				return 0, false
+			}
+			next += 2
+		}
+	case OP_JUMP, OP_JUMP_IF_FALSE, OP_LOOP, OP_FOR_NEXT, OP_TRY, OP_TRY_FINALLY:
+		target := jumpTarget(op, next, operand)
+		return next, target >= 0 && target < len(code)
+	}
+	return next, true
+}
+
+// size is how many bytes an instruction takes with its operands, not counting the upvalues of OP_CLOSURE.
+// It is 0 for bytes that are no instruction.
+func (op OpCode) size() int {
+	switch op {
+	case OP_CONSTANT, OP_GET_GLOBAL, OP_DEFINE_GLOBAL, OP_SET_GLOBAL,
+		OP_GET_PROPERTY, OP_SET_PROPERTY, OP_GET_SUPER,
+		OP_CLASS, OP_METHOD, OP_STATIC_METHOD, OP_IMPORT, OP_CLOSURE,
+		OP_LIST, OP_MAP, OP_INTERPOLATE,
+		OP_JUMP, OP_JUMP_IF_FALSE, OP_LOOP, OP_FOR_NEXT, OP_TRY, OP_TRY_FINALLY:
+		return 3
+	case OP_GET_LOCAL, OP_SET_LOCAL, OP_GET_UPVALUE, OP_SET_UPVALUE, OP_CALL, OP_TAIL_CALL:
+		return 2
+	}
+	if int(op) < len(opNames) {
+		return 1
+	}
+	return 0
+}
+
+// readOperand reads the u8 or u16 operand of the instruction at offset, 0 when it has none
+func readOperand(op OpCode, code []byte, offset int) int {
+	switch op.size() {
+	case 2:
+		return int(code[offset+1])
+	case 3:
+		return int(code[offset+1])<<8 | int(code[offset+2])
+	}
+	return 0
+}
+
+// jumpTarget is where the jump ending at next goes
+func jumpTarget(op OpCode, next int, operand int) int {
+	if op == OP_LOOP {
+		return next - operand
+	}
+	return next + operand
+}
@@ -1,4 +1,4 @@
-package main
+package core
 
 /*
 	GO generics workarounds:
@@ -25,6 +25,11 @@ type Visitor interface {
 	visitGetExpr(expr GetExpr) interface{}
 	visitThisExpr(expr ThisExpr) interface{}
 	visitSuperExpr(expr SuperExpr) interface{}
+	visitInterpolationExpr(expr InterpolationExpr) interface{}
+	visitListExpr(expr ListExpr) interface{}
+	visitIndexExpr(expr IndexExpr) interface{}
+	visitIndexSetExpr(expr IndexSetExpr) interface{}
+	visitMapExpr(expr MapExpr) interface{}
 }
 
 // StmtVisitor is the interface statements visitor should implement
@@ -39,5 +44,12 @@ type StmtVisitor interface {
 	visitBlockStmt(stmt BlockStmt)
 	visitIfStmt(stmt IfStmt)
 	visitWhileStmt(stmt WhileStmt)
+	visitForInStmt(stmt ForInStmt)
+	visitThrowStmt(stmt ThrowStmt)
+	visitTryStmt(stmt TryStmt)
+	visitImportStmt(stmt ImportStmt)
+	visitExportStmt(stmt ExportStmt)
+	visitBreakStmt(stmt BreakStmt)
+	visitContinueStmt(stmt ContinueStmt)
 	visitClassStmt(stmt ClassStmt)
 }
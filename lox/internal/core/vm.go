@@ -0,0 +1,713 @@
+package core
+
+import "strings"
+
+// Closure is a compiled function along with the variables it captured, the VM's Function
+type Closure struct {
+	function *FunctionProto
+	upvalues []*Upvalue
+	// globals are the ones of the module the closure was made in
+	globals *env
+	vm      *VM
+}
+
+func (c *Closure) String() string {
+	return "<fn " + c.function.name + ">"
+}
+
+func (c *Closure) arity() int {
+	return c.function.arity
+}
+
+// call lets natives call back into the VM
+func (c *Closure) call(interpreter Interpreter, args []interface{}) interface{} {
+	return c.vm.callClosure(c, c, args)
+}
+
+// Upvalue is a variable a closure captured,
+// it points at the stack slot of the variable until the variable goes out of scope
+type Upvalue struct {
+	index int
+	open  bool
+	// value holds the variable once it is closed
+	value interface{}
+	// next is the next open upvalue down the stack
+	next *Upvalue
+}
+
+// VMClass is the VM's Class
+type VMClass struct {
+	name          string
+	super         *VMClass
+	staticMethods map[string]*Closure
+	methods       map[string]*Closure
+	fields        map[string]interface{}
+}
+
+func (c *VMClass) String() string {
+	return "<class " + c.name + ">"
+}
+
+func (c *VMClass) findMethod(name string) (*Closure, bool) {
+	method, ok := c.methods[name]
+	if !ok && c.super != nil {
+		method, ok = c.super.findMethod(name)
+	}
+	return method, ok
+}
+
+func (c *VMClass) arity() int {
+	if init, ok := c.findMethod("init"); ok {
+		return init.arity()
+	}
+	return 0
+}
+
+func (c *VMClass) call(interpreter Interpreter, args []interface{}) interface{} {
+	interpreter.lox.allocate(instanceSize, callSite(interpreter), nil)
+	instance := &VMInstance{c, make(map[string]interface{}, 0)}
+	if init, ok := c.findMethod("init"); ok {
+		init.vm.callClosure(init, instance, args)
+	}
+	return instance
+}
+
+func (c *VMClass) get(name Token) (interface{}, error) {
+	if value, ok := c.fields[name.literal]; ok {
+		return value, nil
+	}
+	if method, ok := c.staticMethods[name.literal]; ok {
+		return method, nil
+	}
+	return nil, RuntimeError{
+		name,
+		"Undefined property",
+	}
+}
+
+func (c *VMClass) set(name Token, value interface{}) error {
+	c.fields[name.literal] = value
+	return nil
+}
+
+// VMInstance is the VM's ClassInstance
+type VMInstance struct {
+	class  *VMClass
+	fields map[string]interface{}
+}
+
+func (i *VMInstance) String() string {
+	return "<classInstance " + i.class.name + ">"
+}
+
+func (i *VMInstance) get(name Token) (interface{}, error) {
+	if value, ok := i.fields[name.literal]; ok {
+		return value, nil
+	}
+	if method, ok := i.class.findMethod(name.literal); ok {
+		return &BoundMethod{i, method}, nil
+	}
+	return nil, RuntimeError{
+		name,
+		"Undefined property",
+	}
+}
+
+func (i *VMInstance) set(name Token, value interface{}) error {
+	i.fields[name.literal] = value
+	return nil
+}
+
+// BoundMethod is a method along with the instance it was read from
+type BoundMethod struct {
+	receiver interface{}
+	method   *Closure
+}
+
+func (b *BoundMethod) String() string {
+	return b.method.String()
+}
+
+func (b *BoundMethod) arity() int {
+	return b.method.arity()
+}
+
+func (b *BoundMethod) call(interpreter Interpreter, args []interface{}) interface{} {
+	return b.method.vm.callClosure(b.method, b.receiver, args)
+}
+
+// vmFrame is a call running in the VM
+type vmFrame struct {
+	closure *Closure
+	ip      int
+	// base is the stack slot of the callee, the locals of the call follow it
+	base int
+	// traced frames pushed a CallFrame for stack traces, which returning pops
+	traced bool
+}
+
+// vmHandler is a handler installed by a try statement
+type vmHandler struct {
+	// frames, sp and calls are the sizes of the VM frames, the stack and the traced frames to unwind to
+	frames int
+	sp     int
+	calls  int
+	// ip is where the handler code starts, in the frame that installed it
+	ip      int
+	finally bool
+}
+
+// pendingError is what a finally handler keeps on the stack while the finally block runs,
+// OP_RETHROW raises it again
+type pendingError struct {
+	err interface{}
+	// calls is the stack trace of err, finally blocks make calls of their own
+	calls []CallFrame
+}
+
+/*
+VM runs the bytecode of Compiler on a stack, it is the second engine next to Interpreter.
+Both share the values natives deal with, and the operator semantics,
+so a program prints the same thing whichever engine runs it.
+*/
+type VM struct {
+	lox *Lox
+	// interpreter is what natives are called with, its frames are the call stack errors are traced with
+	interpreter  Interpreter
+	stack        []interface{}
+	frames       []vmFrame
+	handlers     []vmHandler
+	openUpvalues *Upvalue
+	// limits are lox's, every instruction is a step
+	limits *limits
+}
+
+// NewVM makes a VM running with the globals of lox's interpreter
+func NewVM(lox *Lox) *VM {
+	return &VM{
+		lox:         lox,
+		interpreter: lox.interpreter,
+		stack:       make([]interface{}, 0, 256),
+		frames:      make([]vmFrame, 0, 64),
+		limits:      &lox.limits,
+	}
+}
+
+// interpret runs the compiled top level code of the script, and returns what it returns
+func (vm *VM) interpret(function *FunctionProto) interface{} {
+	closure := &Closure{function, nil, vm.interpreter.global, vm}
+	return vm.callClosure(closure, closure, nil)
+}
+
+// reset forgets the calls an error nothing caught unwound, they are never going to return
+func (vm *VM) reset() {
+	vm.stack = vm.stack[:0]
+	vm.frames = vm.frames[:0]
+	vm.handlers = vm.handlers[:0]
+	vm.openUpvalues = nil
+}
+
+// runModule compiles a module and runs its top level code with its own globals, natives included
+func (vm *VM) runModule(name string, src string, globals *env) map[string]bool {
+	function, exports := vm.lox.compileFile(name, src)
+	if vm.lox.hasError {
+		return nil
+	}
+
+	interpreter := vm.interpreter
+	interpreter.global = globals
+	interpreter.init()
+
+	closure := &Closure{function, nil, globals, vm}
+	vm.callClosure(closure, closure, nil)
+	return exports
+}
+
+// callClosure runs closure until it returns, receiver is what its slot 0 holds
+func (vm *VM) callClosure(closure *Closure, receiver interface{}, args []interface{}) interface{} {
+	base := len(vm.stack)
+	vm.push(receiver)
+	vm.stack = append(vm.stack, args...)
+	vm.frames = append(vm.frames, vmFrame{closure, 0, base, false})
+	return vm.run(len(vm.frames) - 1)
+}
+
+// run executes the frame at depth, and whatever it calls, until it returns
+func (vm *VM) run(depth int) interface{} {
+	for {
+		if result, done := vm.execute(depth); done {
+			return result
+		}
+	}
+}
+
+// execute is run for as long as no handler catches an error,
+// done is false when one did and running has to go on from the handler
+func (vm *VM) execute(depth int) (result interface{}, done bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			if !vm.handle(err, depth) {
+				panic(err)
+			}
+		}
+	}()
+
+	return vm.loop(depth), true
+}
+
+// handle unwinds to the innermost handler and hands it what was raised,
+// unless the handler belongs to a frame below depth, which a run further up the go stack is in charge of
+func (vm *VM) handle(err interface{}, depth int) bool {
+	if len(vm.handlers) == 0 {
+		return false
+	}
+	handler := vm.handlers[len(vm.handlers)-1]
+	if handler.frames <= depth {
+		return false
+	}
+
+	value, ok := vm.interpreter.caught(err)
+	if !ok {
+		return false
+	}
+	calls := vm.interpreter.frames
+	if handler.finally {
+		value = &pendingError{err, append([]CallFrame{}, *calls...)}
+	}
+
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.closeUpvalues(handler.sp)
+	vm.stack = vm.stack[:handler.sp]
+	vm.frames = vm.frames[:handler.frames]
+	*calls = (*calls)[:handler.calls]
+	vm.push(value)
+	vm.frames[handler.frames-1].ip = handler.ip
+	return true
+}
+
+func (vm *VM) push(value interface{}) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *VM) pop() interface{} {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+func (vm *VM) peek(distance int) interface{} {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+// loop is the instruction dispatch loop
+func (vm *VM) loop(depth int) interface{} {
+	for {
+		// calls may have grown the frames, the frame is looked up again for every instruction
+		frame := &vm.frames[len(vm.frames)-1]
+		chunk := &frame.closure.function.chunk
+		code := chunk.code
+		start := frame.ip
+		op := OpCode(code[start])
+		frame.ip++
+		vm.limits.step()
+
+		switch op {
+		case OP_CONSTANT:
+			vm.push(chunk.constants[vm.readShort(frame)])
+		case OP_NIL:
+			vm.push(nil)
+		case OP_TRUE:
+			vm.push(true)
+		case OP_FALSE:
+			vm.push(false)
+		case OP_POP:
+			vm.pop()
+
+		case OP_GET_LOCAL:
+			vm.push(vm.stack[frame.base+int(vm.readByte(frame))])
+		case OP_SET_LOCAL:
+			vm.stack[frame.base+int(vm.readByte(frame))] = vm.peek(0)
+		case OP_GET_GLOBAL:
+			name := chunk.constants[vm.readShort(frame)].(string)
+			value, ok := frame.closure.globals.values[name]
+			if !ok {
+				panic(RuntimeError{chunk.token(start), "Undefined variable: " + name})
+			}
+			vm.push(value)
+		case OP_DEFINE_GLOBAL:
+			name := chunk.constants[vm.readShort(frame)].(string)
+			frame.closure.globals.values[name] = vm.pop()
+		case OP_SET_GLOBAL:
+			name := chunk.constants[vm.readShort(frame)].(string)
+			if _, ok := frame.closure.globals.values[name]; !ok {
+				panic(RuntimeError{chunk.token(start), "Undefined variable: " + name})
+			}
+			frame.closure.globals.values[name] = vm.peek(0)
+		case OP_GET_UPVALUE:
+			upvalue := frame.closure.upvalues[vm.readByte(frame)]
+			if upvalue.open {
+				vm.push(vm.stack[upvalue.index])
+			} else {
+				vm.push(upvalue.value)
+			}
+		case OP_SET_UPVALUE:
+			upvalue := frame.closure.upvalues[vm.readByte(frame)]
+			if upvalue.open {
+				vm.stack[upvalue.index] = vm.peek(0)
+			} else {
+				upvalue.value = vm.peek(0)
+			}
+
+		case OP_GET_PROPERTY:
+			vm.readShort(frame)
+			name := chunk.token(start)
+			object := vm.pop()
+			value := getProperty(name, object)
+			vm.lox.allocate(propertySize(object, name, value), name, nil)
+			vm.push(value)
+		case OP_SET_PROPERTY:
+			vm.readShort(frame)
+			name := chunk.token(start)
+			value := vm.pop()
+			object := checkObject(name, vm.pop())
+			vm.lox.allocate(entrySize+len(name.literal), name, nil)
+			if err := object.set(name, value); err != nil {
+				panic(err)
+			}
+			vm.push(value)
+		case OP_GET_SUPER:
+			name := chunk.constants[vm.readShort(frame)].(string)
+			this := vm.pop()
+			super := vm.pop().(*VMClass)
+			method, ok := super.findMethod(name)
+			if !ok {
+				panic(RuntimeError{
+					chunk.token(start),
+					"Undefined property name: '" + name + "'",
+				})
+			}
+			vm.lox.allocate(objectSize, chunk.token(start), nil)
+			vm.push(&BoundMethod{this, method})
+		case OP_GET_INDEX:
+			i := vm.pop()
+			object := vm.pop()
+			value := getIndex(chunk.token(start), object, i)
+			// indexing a string makes a string of the character
+			if _, ok := object.(string); ok {
+				vm.lox.allocate(sizeOf(value), chunk.token(start), nil)
+			}
+			vm.push(value)
+		case OP_SET_INDEX:
+			bracket := chunk.token(start)
+			value := vm.pop()
+			i := vm.pop()
+			indexable := checkIndexable(bracket, vm.pop())
+			if _, ok := indexable.(*Map); ok {
+				vm.lox.allocate(entrySize+interfaceSize, bracket, nil)
+			}
+			indexable.setIndex(bracket, i, value)
+			vm.push(value)
+
+		case OP_ADD:
+			right, left := vm.pop(), vm.pop()
+			if l, ok := left.(float64); ok {
+				if r, ok := right.(float64); ok {
+					vm.push(l + r)
+					continue
+				}
+			}
+			value := binary(chunk.token(start), left, right)
+			if str, ok := value.(string); ok {
+				vm.lox.allocate(sizeOf(str), chunk.token(start), nil)
+			}
+			vm.push(value)
+		case OP_SUBTRACT:
+			right, left := vm.pop(), vm.pop()
+			if l, ok := left.(float64); ok {
+				if r, ok := right.(float64); ok {
+					vm.push(l - r)
+					continue
+				}
+			}
+			vm.push(binary(chunk.token(start), left, right))
+		case OP_LESS:
+			right, left := vm.pop(), vm.pop()
+			if l, ok := left.(float64); ok {
+				if r, ok := right.(float64); ok {
+					vm.push(l < r)
+					continue
+				}
+			}
+			vm.push(binary(chunk.token(start), left, right))
+		case OP_EQUAL, OP_NOT_EQUAL, OP_GREATER, OP_GREATER_EQUAL, OP_LESS_EQUAL, OP_MULTIPLY, OP_DIVIDE:
+			right, left := vm.pop(), vm.pop()
+			vm.push(binary(chunk.token(start), left, right))
+		case OP_NOT:
+			vm.push(!toBool(vm.pop()))
+		case OP_NEGATE:
+			vm.push(unary(chunk.token(start), vm.pop()))
+
+		case OP_JUMP:
+			offset := vm.readShort(frame)
+			frame.ip += offset
+		case OP_JUMP_IF_FALSE:
+			offset := vm.readShort(frame)
+			if !toBool(vm.peek(0)) {
+				frame.ip += offset
+			}
+		case OP_LOOP:
+			offset := vm.readShort(frame)
+			frame.ip -= offset
+
+		case OP_CALL:
+			argc := int(vm.readByte(frame))
+			vm.callValue(vm.peek(argc), argc, chunk.token(start))
+		case OP_TAIL_CALL:
+			argc := int(vm.readByte(frame))
+			vm.tailCall(frame, vm.peek(argc), argc, chunk.token(start))
+		case OP_CLOSURE:
+			function := chunk.constants[vm.readShort(frame)].(*FunctionProto)
+			vm.lox.allocate(functionSize+wordSize*function.upvalueCount, chunk.token(start), nil)
+			closure := &Closure{function, make([]*Upvalue, function.upvalueCount), frame.closure.globals, vm}
+			// the closure is on the stack before capturing, a local function captures itself
+			vm.push(closure)
+			for i := range closure.upvalues {
+				isLocal := vm.readByte(frame)
+				index := int(vm.readByte(frame))
+				if isLocal == 1 {
+					closure.upvalues[i] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.upvalues[i] = frame.closure.upvalues[index]
+				}
+			}
+		case OP_CLOSE_UPVALUE:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case OP_RETURN:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			if frame.traced {
+				calls := vm.interpreter.frames
+				*calls = (*calls)[:len(*calls)-1]
+			}
+			vm.stack = vm.stack[:frame.base]
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) == depth {
+				return result
+			}
+			vm.push(result)
+
+		case OP_CLASS:
+			name := chunk.constants[vm.readShort(frame)].(string)
+			vm.push(&VMClass{
+				name,
+				nil,
+				make(map[string]*Closure, 0),
+				make(map[string]*Closure, 0),
+				make(map[string]interface{}, 0),
+			})
+		case OP_INHERIT:
+			class := vm.pop().(*VMClass)
+			super, ok := vm.peek(0).(*VMClass)
+			if !ok {
+				name := chunk.token(start)
+				panic(RuntimeError{
+					name,
+					name.literal + " is not a class",
+				})
+			}
+			class.super = super
+		case OP_METHOD:
+			name := chunk.constants[vm.readShort(frame)].(string)
+			method := vm.pop().(*Closure)
+			vm.peek(0).(*VMClass).methods[name] = method
+		case OP_STATIC_METHOD:
+			name := chunk.constants[vm.readShort(frame)].(string)
+			method := vm.pop().(*Closure)
+			vm.peek(0).(*VMClass).staticMethods[name] = method
+
+		case OP_LIST:
+			count := vm.readShort(frame)
+			elements := make([]interface{}, count)
+			copy(elements, vm.stack[len(vm.stack)-count:])
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			list := NewList(elements)
+			vm.lox.allocate(sizeOf(list), chunk.token(start), nil)
+			vm.push(list)
+		case OP_MAP:
+			count := vm.readShort(frame)
+			entries := vm.stack[len(vm.stack)-2*count:]
+			m := NewMap()
+			for i := 0; i < len(entries); i += 2 {
+				checkKey(chunk.token(start), entries[i])
+				m.put(entries[i], entries[i+1])
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2*count]
+			vm.lox.allocate(sizeOf(m), chunk.token(start), nil)
+			vm.push(m)
+		case OP_INTERPOLATE:
+			count := vm.readShort(frame)
+			var b strings.Builder
+			for _, part := range vm.stack[len(vm.stack)-count:] {
+				b.WriteString(stringify(part))
+			}
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			str := b.String()
+			vm.lox.allocate(sizeOf(str), chunk.token(start), nil)
+			vm.push(str)
+
+		case OP_ITERATOR:
+			vm.push(vm.interpreter.iterate(chunk.token(start), vm.pop()))
+		case OP_FOR_NEXT:
+			offset := vm.readShort(frame)
+			iterator := vm.peek(0).(Iterator)
+			if iterator.hasNext() {
+				vm.push(iterator.next())
+			} else {
+				// the iterator may have called into lox, which can grow the frames
+				vm.frames[len(vm.frames)-1].ip += offset
+			}
+
+		case OP_THROW:
+			panic(ThrowSignal{chunk.token(start), vm.pop()})
+		case OP_TRY, OP_TRY_FINALLY:
+			offset := vm.readShort(frame)
+			vm.handlers = append(vm.handlers, vmHandler{
+				frames:  len(vm.frames),
+				sp:      len(vm.stack),
+				calls:   len(*vm.interpreter.frames),
+				ip:      frame.ip + offset,
+				finally: op == OP_TRY_FINALLY,
+			})
+		case OP_POP_HANDLER:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case OP_RETHROW:
+			pending := vm.pop().(*pendingError)
+			*vm.interpreter.frames = pending.calls
+			panic(pending.err)
+
+		case OP_IMPORT:
+			vm.readShort(frame)
+			vm.push(vm.lox.importModule(chunk.token(start), vm.runModule))
+
+		default:
+			panic(RuntimeError{chunk.token(start), "Unknown opcode " + op.String()})
+		}
+	}
+}
+
+func (vm *VM) readByte(frame *vmFrame) byte {
+	b := frame.closure.function.chunk.code[frame.ip]
+	frame.ip++
+	return b
+}
+
+func (vm *VM) readShort(frame *vmFrame) int {
+	code := frame.closure.function.chunk.code
+	frame.ip += 2
+	return int(code[frame.ip-2])<<8 | int(code[frame.ip-1])
+}
+
+// callValue calls the callee below its argc arguments on the stack, as if it was called at paren.
+// Lox functions get a frame and run in the dispatch loop, natives are called right away.
+func (vm *VM) callValue(callee interface{}, argc int, paren Token) {
+	function := checkCallable(paren, callee, argc)
+	calls := vm.interpreter.frames
+	vm.limits.call(len(*calls))
+	slot := len(vm.stack) - argc - 1
+
+	switch callee := callee.(type) {
+	case *Closure:
+		vm.pushFrame(callee, slot, paren)
+	case *BoundMethod:
+		vm.stack[slot] = callee.receiver
+		vm.pushFrame(callee.method, slot, paren)
+	case *VMClass:
+		vm.lox.allocate(instanceSize, paren, nil)
+		instance := &VMInstance{callee, make(map[string]interface{}, 0)}
+		vm.stack[slot] = instance
+		if init, ok := callee.findMethod("init"); ok {
+			// the constructor shows up under the name of the class
+			*calls = append(*calls, CallFrame{callee.name, paren, nil})
+			vm.frames = append(vm.frames, vmFrame{init, 0, slot, true})
+		}
+	default:
+		args := make([]interface{}, argc)
+		copy(args, vm.stack[slot+1:])
+		// frames are only popped on normal returns, like Interpreter.call does
+		*calls = append(*calls, CallFrame{frameName(function), paren, nil})
+		result := function.call(vm.interpreter, args)
+		*calls = (*calls)[:len(*calls)-1]
+		vm.stack = vm.stack[:slot]
+		vm.push(result)
+	}
+}
+
+// tailCall makes a call in tail position, a closure runs in the frame of its caller so deep recursion keeps the frames flat.
+// Anything else is called like OP_CALL would.
+func (vm *VM) tailCall(frame *vmFrame, callee interface{}, argc int, paren Token) {
+	var receiver interface{} = callee
+	closure, ok := callee.(*Closure)
+	if method, isMethod := callee.(*BoundMethod); isMethod {
+		receiver, closure, ok = method.receiver, method.method, true
+	}
+	if !ok {
+		vm.callValue(callee, argc, paren)
+		return
+	}
+	checkCallable(paren, callee, argc)
+
+	// the callee and its arguments take the place of the caller's
+	vm.closeUpvalues(frame.base)
+	slot := len(vm.stack) - argc - 1
+	vm.stack[slot] = receiver
+	copy(vm.stack[frame.base:], vm.stack[slot:])
+	vm.stack = vm.stack[:frame.base+argc+1]
+	frame.closure = closure
+	frame.ip = 0
+
+	// like Function.call, tracebacks show where the chain of tail calls began
+	if calls := *vm.interpreter.frames; len(calls) > 0 {
+		calls[len(calls)-1].name = closure.function.frameName()
+	}
+}
+
+func (vm *VM) pushFrame(closure *Closure, slot int, paren Token) {
+	vm.lox.allocate(envSize+interfaceSize*closure.function.arity, paren, nil)
+	calls := vm.interpreter.frames
+	*calls = append(*calls, CallFrame{closure.function.frameName(), paren, nil})
+	vm.frames = append(vm.frames, vmFrame{closure, 0, slot, true})
+}
+
+// captureUpvalue returns the upvalue of the stack slot at index, closures capturing the same variable share it
+func (vm *VM) captureUpvalue(index int) *Upvalue {
+	var previous *Upvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.index > index {
+		previous = upvalue
+		upvalue = upvalue.next
+	}
+	if upvalue != nil && upvalue.index == index {
+		return upvalue
+	}
+
+	// the variable moves into the upvalue once it goes out of scope, closures keep it alive
+	vm.lox.allocate(upvalueSize, unplaced, nil)
+	created := &Upvalue{index: index, open: true, next: upvalue}
+	if previous == nil {
+		vm.openUpvalues = created
+	} else {
+		previous.next = created
+	}
+	return created
+}
+
+// closeUpvalues moves the variables from last up the stack into their upvalues, they are going out of scope
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.index >= last {
+		upvalue := vm.openUpvalues
+		upvalue.value = vm.stack[upvalue.index]
+		upvalue.open = false
+		vm.openUpvalues = upvalue.next
+	}
+}
@@ -0,0 +1,13 @@
+package lox
+
+import "github.com/blackLearning/glox/lox/internal/core"
+
+// errors a Runtime returns for programs a limit stopped, see Options
+var (
+	ErrStepLimit  = core.ErrStepLimit
+	ErrDepthLimit = core.ErrDepthLimit
+)
+
+// DefaultMaxDepth is how deep calls nest when Options.MaxDepth is 0.
+// It is far from overflowing the go stack, which would take the host down with it.
+const DefaultMaxDepth = core.DefaultMaxDepth
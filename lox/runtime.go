@@ -0,0 +1,178 @@
+package lox
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/blackLearning/glox/lox/internal/core"
+)
+
+// Options configure a Runtime
+type Options struct {
+	// VM runs programs on the bytecode VM instead of the tree-walking interpreter
+	VM bool
+	// Stdout is where print writes, os.Stdout when nil
+	Stdout io.Writer
+	// Stderr is where diagnostics are rendered the way the glox command prints them.
+	// When nil they are only returned, as *Error.
+	Stderr io.Writer
+	// MaxSteps stops programs running more steps than this with ErrStepLimit, 0 means no limit.
+	// A step is a statement on the interpreter and an instruction on the VM.
+	MaxSteps int
+	// MaxDepth stops programs nesting more calls than this with ErrDepthLimit, 0 means DefaultMaxDepth
+	// and a negative value no limit. Tail calls don't nest.
+	// Without a limit, deep recursion on the interpreter can overflow the go stack, which can't be recovered from.
+	MaxDepth int
+	// MaxMemory is roughly how many bytes the values of a program may hold, 0 means no limit.
+	// Strings, lists, maps, instances, environments and closures count,
+	// a program needing more raises a runtime error, which scripts can catch.
+	// What a program holds is measured as it allocates, it can go over the limit by a quarter of it before it is stopped,
+	// and values held only while an expression is evaluated, like the operands of an operator, are not counted.
+	MaxMemory int
+	// Allow lists the capabilities scripts may use, like CapabilityIO, the natives of the others raise when called.
+	// print, Error and memoryUsage need none. Any other name is an error.
+	Allow []string
+}
+
+// Runtime compiles and runs lox programs for a go host.
+// Globals, imported modules and functions live as long as the Runtime,
+// so a program run by Eval sees what earlier ones defined.
+// A Runtime is not safe for concurrent use.
+type Runtime struct {
+	runtime *core.Runtime
+}
+
+// Error is a program that failed to compile, or an error nothing caught while it ran
+type Error struct {
+	// Code tells which phase failed, CodeRuntime for errors raised while running
+	Code    string
+	Message string
+	// File, Line and Column locate the error, Line is 0 when it has no place in the source
+	File   string
+	Line   int
+	Column int
+	// Trace is the stack traceback of a runtime error, outermost call first
+	Trace []string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// Codes of an Error, CodeRuntime for errors raised while running, one for each phase of compiling otherwise
+const (
+	CodeLexical = core.CodeLexical
+	CodeSyntax  = core.CodeSyntax
+	CodeResolve = core.CodeResolve
+	CodeRuntime = core.CodeRuntime
+	CodeCompile = core.CodeCompile
+)
+
+// NewRuntime makes a Runtime with the lox natives defined as globals.
+// It fails when options allow a capability glox does not know.
+func NewRuntime(options Options) (*Runtime, error) {
+	runtime, err := core.NewRuntime(core.Options{
+		VM:        options.VM,
+		Stdout:    options.Stdout,
+		Stderr:    options.Stderr,
+		MaxSteps:  options.MaxSteps,
+		MaxDepth:  options.MaxDepth,
+		MaxMemory: options.MaxMemory,
+		Allow:     options.Allow,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Runtime{runtime}, nil
+}
+
+// Eval runs src as a program, and returns the value of its last statement when that is an expression.
+// Relative imports are looked up from the working directory.
+// Every Eval is numbered from line 1 in errors.
+func (r *Runtime) Eval(src string) (interface{}, error) {
+	return r.EvalContext(context.Background(), src)
+}
+
+// EvalContext is Eval stopping the program with ctx.Err() once ctx is done
+func (r *Runtime) EvalContext(ctx context.Context, src string) (interface{}, error) {
+	value, err := r.runtime.EvalContext(ctx, src)
+	return value, convertError(err)
+}
+
+// RunFile runs the script at path. An error reading it is returned as it is,
+// failing to compile or to run is returned as *Error, and a limit stopping it as the error of the limit.
+func (r *Runtime) RunFile(path string) error {
+	return r.RunFileContext(context.Background(), path)
+}
+
+// RunFileContext is RunFile stopping the program with ctx.Err() once ctx is done
+func (r *Runtime) RunFileContext(ctx context.Context, path string) error {
+	return convertError(r.runtime.RunFileContext(ctx, path))
+}
+
+// SetGlobal defines a global every program run afterwards can use, value is converted as ToLox does.
+// This is how go funcs and structs are registered:
+//
+//	runtime.SetGlobal("repeat", func(s string, n int) (string, error) { ... })
+func (r *Runtime) SetGlobal(name string, value interface{}) error {
+	return r.runtime.SetGlobal(name, value)
+}
+
+// SetNative defines a global native of capability, scripts can only call it when the capability is allowed.
+// fn is converted as ToLox does.
+func (r *Runtime) SetNative(capability string, name string, fn interface{}) error {
+	return r.runtime.SetNative(capability, name, fn)
+}
+
+// GetGlobal reads a global, converted as ToGo does. ok is false when it is not defined.
+func (r *Runtime) GetGlobal(name string) (value interface{}, ok bool) {
+	return r.runtime.GetGlobal(name)
+}
+
+// Call calls a lox function, method, class or native and returns what it returns, converted as ToGo does.
+// fn is either a value Eval, GetGlobal or Call returned, or the name of a global.
+// args are converted as ToLox does.
+func (r *Runtime) Call(fn interface{}, args ...interface{}) (interface{}, error) {
+	return r.CallContext(context.Background(), fn, args...)
+}
+
+// CallContext is Call stopping the call with ctx.Err() once ctx is done
+func (r *Runtime) CallContext(ctx context.Context, fn interface{}, args ...interface{}) (interface{}, error) {
+	value, err := r.runtime.CallContext(ctx, fn, args...)
+	return value, convertError(err)
+}
+
+// DumpAST prints the script at path to out the way the optimizer leaves it, without running it
+func (r *Runtime) DumpAST(out io.Writer, path string) error {
+	return convertError(r.runtime.DumpAST(out, path))
+}
+
+// Disassemble prints to out the bytecode the VM runs for the script at path
+func (r *Runtime) Disassemble(out io.Writer, path string) error {
+	return convertError(r.runtime.Disassemble(out, path))
+}
+
+// REPL reads programs from in and runs them until in ends, bare expressions get their value printed.
+// Prompts and values go to Options.Stdout, diagnostics to Options.Stderr.
+func (r *Runtime) REPL(in io.Reader) {
+	r.runtime.REPL(in)
+}
+
+// convertError turns the errors of programs that failed into *Error, and leaves the others as they are
+func convertError(err error) error {
+	if err, ok := err.(*core.Error); ok {
+		return &Error{
+			Code:    err.Code,
+			Message: err.Message,
+			File:    err.File,
+			Line:    err.Line,
+			Column:  err.Column,
+			Trace:   err.Trace,
+		}
+	}
+	return err
+}
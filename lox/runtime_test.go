@@ -0,0 +1,26 @@
+package lox
+
+import "testing"
+
+func TestRuntime(t *testing.T) {
+	runtime, err := NewRuntime(Options{VM: true, Allow: []string{CapabilityTime}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runtime.Eval("fun add(a, b) { return a + b; }\nadd(1, nil);"); err == nil {
+		t.Fatal("adding nil ran")
+	} else if lerr, ok := err.(*Error); !ok || lerr.Code != CodeRuntime || lerr.File != "<eval>" || lerr.Line != 1 || len(lerr.Trace) != 2 {
+		t.Errorf("adding nil failed with %#v", err)
+	}
+
+	add, _ := runtime.GetGlobal("add")
+	if _, ok := add.(Ref); !ok {
+		t.Fatalf("a function came back as %T", add)
+	}
+	if value, err := runtime.Call(add, 1, 2); err != nil || value != 3.0 {
+		t.Errorf("add(1, 2) is %v, %v", value, err)
+	}
+	if _, err := runtime.Eval("fun f(n) { return 1 + f(n); } f(1);"); err != ErrDepthLimit {
+		t.Errorf("runaway recursion ended with %v", err)
+	}
+}
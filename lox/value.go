@@ -0,0 +1,57 @@
+package lox
+
+import "github.com/blackLearning/glox/lox/internal/core"
+
+// Ref is a lox value go has no counterpart for, like a function, a class, an instance or a module.
+// It goes back to lox as the very same value, Runtime.Call calls the callable ones.
+type Ref = core.Ref
+
+// ToGo converts a lox value for go:
+//
+//	nil, booleans, numbers, strings   nil, bool, float64, string
+//	lists                             []interface{}, a copy with its elements converted
+//	maps                              map[interface{}]interface{}, a copy with its values converted
+//	go structs                        the pointer to the struct
+//	anything else                     Ref
+//
+// A list or a map holding itself converts to a slice or a map holding itself.
+func ToGo(value interface{}) interface{} {
+	return core.ToGo(value)
+}
+
+// ToLox converts a go value for lox, the other way around ToGo does:
+//
+//	nil, bool, string                 nil, booleans, strings
+//	any integer or float              numbers
+//	slices and arrays                 lists, with their elements converted
+//	maps                              maps, keys in sorted order, they must convert to nil, booleans, numbers or strings
+//	funcs                             natives, see below
+//	pointers to structs               objects sharing the struct, see below
+//	structs                           objects holding a copy of the struct
+//	Ref                               the value it holds
+//
+// Any other value is an error.
+//
+// The arguments of a call to a func are converted to the types of its parameters, the other way around,
+// a call with an argument that does not convert raises a runtime error.
+// Lists and maps convert to slices and maps, objects to the pointer to their struct or to a copy of it,
+// and anything else to Ref or to an interface{} as ToGo converts it.
+// The func returns nothing, a value, an error, or a value and an error,
+// a non nil error is raised as a runtime error with its message, which scripts can catch.
+//
+// The properties of an object are the exported fields and methods of its struct.
+// The field tagged `lox:"name"` is property name,
+// other fields and methods are named like the property with its first letter in upper case:
+//
+//	type Point struct {
+//		X, Y  float64
+//		Label string `lox:"label"`
+//	}
+//
+//	func (p *Point) Scale(by float64) { p.X *= by; p.Y *= by }
+//
+// is used in lox as `point.x`, `point.label` and `point.scale(2)`.
+// Setting a property converts the value to the type of its field.
+func ToLox(value interface{}) (interface{}, error) {
+	return core.ToLox(value)
+}
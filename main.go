@@ -1,109 +1,98 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"os"
+	"strings"
+
+	"github.com/blackLearning/glox/lox"
 )
 
-type Lox struct {
-	errorReporter *ErrorReporter
-	scanner       *Scanner
-	parser        *Parser
-	hasError      bool
-}
+// exit codes, following the BSD sysexits convention
+const (
+	exitCompileError = 65
+	exitRuntimeError = 70
+)
 
 func main() {
-	args := os.Args[1:]
-
-	lox := &Lox{
-		errorReporter: &ErrorReporter{},
-		parser:        &Parser{},
-		scanner: &Scanner{
-			tokens: []Token{},
-		},
+	useVM := flag.Bool("vm", false, "run scripts on the bytecode VM instead of the tree-walking interpreter")
+	dumpAST := flag.Bool("dump-ast", false, "print the optimized AST of the script instead of running it")
+	maxSteps := flag.Int("max-steps", 0, "stop the script after this many steps, 0 means no limit")
+	maxDepth := flag.Int("max-depth", lox.DefaultMaxDepth, "stop the script when calls nest deeper than this, 0 means no limit")
+	maxMemory := flag.Int("max-memory", 0, "raise a runtime error when the script holds more bytes than this, 0 means no limit")
+	timeout := flag.Duration("timeout", 0, "stop the script after running this long, 0 means no limit")
+	allow := flag.String("allow", "", "comma separated capabilities the script may use: io, os, time, net, process, none by default")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "[GLOX]: Usage: glox [--vm] [--dump-ast] [--max-steps n] [--max-depth n] [--max-memory n] [--timeout d] [--allow=caps] [script]")
+		fmt.Fprintln(os.Stderr, "              glox disasm script")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if *maxDepth == 0 {
+		*maxDepth = -1
 	}
-	lox.scanner.lox = lox
-	lox.errorReporter.lox = lox
-	lox.parser.lox = lox
 
-	if len(args) > 1 {
-		fmt.Println("GLOX]: Usage: glox [script]")
-		os.Exit(1)
-	} else if len(args) == 1 {
-		lox.runFile(args[0])
-	} else {
-		lox.runREPL()
+	// an empty --allow allows nothing
+	capabilities := make([]string, 0)
+	for _, capability := range strings.Split(*allow, ",") {
+		if capability = strings.TrimSpace(capability); capability != "" {
+			capabilities = append(capabilities, capability)
+		}
 	}
-}
 
-func (l *Lox) runFile(path string) {
-	content, err := ioutil.ReadFile(path)
+	runtime, err := lox.NewRuntime(lox.Options{
+		VM:        *useVM,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+		MaxSteps:  *maxSteps,
+		MaxDepth:  *maxDepth,
+		MaxMemory: *maxMemory,
+		Allow:     capabilities,
+	})
 	if err != nil {
-		fmt.Println("[GLOX]: unvalid filepath")
+		fmt.Fprintln(os.Stderr, "[GLOX]:", err)
 		os.Exit(1)
 	}
-
-	l.run(string(content))
-}
-
-func (l *Lox) runREPL() {
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		l.run(scanner.Text())
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
 	}
 
-	if scanner.Err() != nil {
-		// handle error.
+	if len(args) == 2 && args[0] == "disasm" {
+		exit(runtime.Disassemble(os.Stdout, args[1]))
+	} else if len(args) > 1 {
+		flag.Usage()
+		os.Exit(1)
+	} else if len(args) == 1 && *dumpAST {
+		exit(runtime.DumpAST(os.Stdout, args[0]))
+	} else if len(args) == 1 {
+		exit(runtime.RunFileContext(ctx, args[0]))
+	} else {
+		runtime.REPL(os.Stdin)
 	}
 }
 
-func (l *Lox) run(src string) {
-	l.scanner.tokens = l.parser.tokens[0:0] // empty slice
-	l.parser.reset()
-
-	l.scanner.source = src
-	l.scanner.scanTokens()
-	l.parser.tokens = l.scanner.tokens
-	// fmt.Println("tokens: ", l.parser.tokens)
-	stmts := l.parser.parse()
-	if l.hasError {
-		return
-	}
-	// check if our AST works as we expect
-	// AstPrinter{}.print(expr, os.Stdout)
-
-	// Evaluating statements
-	interpreter := NewInterpreter(l, env{
-		values: make(map[string]interface{}, 0),
-		parent: nil,
-	})
-	resolver := NewResolver(l, &interpreter)
-	resolver.resolveBody(stmts)
-
-	// Stop if there was a resolution error.
-	if l.hasError {
+// exit ends glox the way err says the script went, its diagnostics are already on stderr
+func exit(err error) {
+	switch err := err.(type) {
+	case nil:
 		return
+	case *lox.Error:
+		if err.Code == lox.CodeRuntime {
+			os.Exit(exitRuntimeError)
+		}
+		os.Exit(exitCompileError)
+	case *os.PathError:
+		fmt.Println("[GLOX]: unvalid filepath")
+		os.Exit(1)
+	default:
+		// a limit stopped the script
+		fmt.Fprintln(os.Stderr, "[GLOX]:", err)
+		os.Exit(exitRuntimeError)
 	}
-
-	interpreter.executeBlock(stmts)
-}
-
-// you will likely have multiple ways errors get displayed
-// on stderr, in an IDE’s error window, logged to a file, etc.
-type ErrorReporter struct {
-	lox *Lox
-}
-
-func (e *ErrorReporter) error(err error) {
-	e.lox.hasError = true
-	// by default, we just print on the interface and then exit with 1
-	log.Fatal(err)
-}
-
-func (e *ErrorReporter) errorWithoutExit(err error) {
-	e.lox.hasError = true
-	fmt.Println(err)
 }
@@ -21,25 +21,37 @@ func main() {
 		"SetExpr    : object Expr,name Token,value Expr",
 		"ThisExpr    : keyword Token",
 		"IdentifierExpr    : name Token",
-		"FunExpr    : params []Token,body BlockStmt",
+		"FunExpr    : keyword Token,params []Token,body BlockStmt",
 		"SuperExpr    : keyword Token,method Token",
-	}, "expr.go", exprTemplate)
+		"InterpolationExpr    : parts []Expr",
+		"ListExpr    : bracket Token,elements []Expr",
+		"IndexExpr    : object Expr,bracket Token,index Expr",
+		"IndexSetExpr    : object Expr,bracket Token,index Expr,value Expr",
+		"MapExpr    : brace Token,keys []Expr,values []Expr",
+	}, "lox/internal/core/expr.go", exprTemplate)
 
 	generateAst("Stmt", []string{
 		"ExpressionStmt   : expression Expr",
 		// "PrintStmt    : expression Expr",
 		"BlockStmt    : statements []Stmt",
-		"VarStmt    	: name Token, init Expr",
-		"ClassStmt    : name Token, super *IdentifierExpr, methods []FunStmt, staticMethods []FunStmt",
+		"VarStmt    	: name Token, init Expr, doc string",
+		"ClassStmt    : name Token, super *IdentifierExpr, methods []FunStmt, staticMethods []FunStmt, doc string",
 		"ReturnStmt   : keyword Token, value Expr",
-		"FunStmt    	: name Token, params []Token, body BlockStmt",
+		"FunStmt    	: name Token, params []Token, body BlockStmt, doc string",
 		"IfStmt    		: condition Expr, consequent Stmt, alternate Stmt",
-		"WhileStmt    : condition Expr, body Stmt",
-	}, "stmt.go", stmtTemplate)
+		"WhileStmt    : condition Expr, body Stmt, increment Expr, label Token",
+		"ForInStmt    : name Token, keyword Token, iterable Expr, body Stmt, label Token",
+		"ThrowStmt    : keyword Token, value Expr",
+		"TryStmt    	: body BlockStmt, name Token, catchBody *BlockStmt, finallyBody *BlockStmt",
+		"ImportStmt    : keyword Token, path Token, name Token",
+		"ExportStmt    : keyword Token, declaration Stmt",
+		"BreakStmt    : keyword Token, label Token",
+		"ContinueStmt    : keyword Token, label Token",
+	}, "lox/internal/core/stmt.go", stmtTemplate)
 }
 
 const exprTemplate = `
-package main
+package core
 
 type {{.Super}} interface {
 	accept(visitor Visitor) interface{}
@@ -57,7 +69,7 @@ func (s {{$v.Name}}) accept(visitor Visitor) interface{} {
 {{ end }}
 `
 const stmtTemplate = `
-package main
+package core
 
 type {{.Super}} interface {
 	accept(visitor StmtVisitor)